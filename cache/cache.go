@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+type PanicError struct {
+	Recover any
+	Stack   []byte
+}
+
+func (p *PanicError) Error() string {
+	return "cache: panic occurred"
+}
+
+func IsPanicError(err error) bool {
+	var panicError *PanicError
+	return errors.As(err, &panicError)
+}
+
+// Cache serves results of a function from a Store, refreshes entries in the background when
+// they are about to expire and serves expired entries when the dependency is unavailable.
+type Cache[K comparable, T any] struct {
+	name   string
+	store  Store[K, T]
+	config Config
+
+	mu         sync.Mutex
+	refreshing map[K]struct{}
+}
+
+func New[K comparable, T any](name string, store Store[K, T], opts ...Option) *Cache[K, T] {
+	config := defaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &Cache[K, T]{
+		name:       name,
+		store:      store,
+		config:     config,
+		refreshing: make(map[K]struct{}),
+	}
+}
+
+func (c *Cache[K, T]) Name() string {
+	return c.name
+}
+
+// Invalidate removes the entry for key, the next call loads it again
+func (c *Cache[K, T]) Invalidate(ctx context.Context, key K) error {
+	return c.store.Delete(ctx, key)
+}
+
+func (c *Cache[K, T]) set(ctx context.Context, key K, value T) {
+	entry := Entry[T]{
+		Value:    value,
+		StoredAt: c.config.Now(),
+	}
+
+	// a failed write only costs a load on the next call, it must not fail a call that succeeded
+	_ = c.store.Set(ctx, key, entry, c.config.TTL+c.config.StaleTTL)
+}
+
+func (c *Cache[K, T]) refresh(ctx context.Context, key K, fn func(context.Context) (T, error)) {
+	c.mu.Lock()
+	if _, ok := c.refreshing[key]; ok {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = struct{}{}
+	c.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+
+		start := c.config.Now()
+
+		result, err := safeExecute(ctx, fn)
+		if err == nil {
+			c.set(ctx, key, result)
+		}
+
+		c.metricsReporter().RecordRefresh(
+			ctx, Refresh{
+				Name:     c.name,
+				Duration: c.config.Now().Sub(start),
+				Error:    err,
+			},
+		)
+	}()
+}
+
+func (c *Cache[K, T]) softExpired(age time.Duration) bool {
+	return c.config.SoftTTL > 0 && c.config.SoftTTL < c.config.TTL && age >= c.config.SoftTTL
+}
+
+func (c *Cache[K, T]) recordLookup(ctx context.Context, result LookupResult) {
+	c.metricsReporter().RecordLookup(
+		ctx, Lookup{
+			Name:   c.name,
+			Result: result,
+		},
+	)
+}
+
+func (c *Cache[K, T]) metricsReporter() Metrics {
+	if c.config.Metrics != nil {
+		return c.config.Metrics
+	}
+
+	return GetGlobalMetrics()
+}
+
+func safeExecute[T any](ctx context.Context, fn func(context.Context) (T, error)) (result T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{
+				Recover: r,
+				Stack:   debug.Stack(),
+			}
+		}
+	}()
+
+	return fn(ctx)
+}
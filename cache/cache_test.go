@@ -0,0 +1,224 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/backoff"
+	"github.com/hugolhafner/dskit/cache"
+	"github.com/hugolhafner/dskit/circuitbreaker"
+	"github.com/hugolhafner/dskit/retry"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type recordingMetrics struct {
+	mu        sync.Mutex
+	lookups   map[cache.LookupResult]int
+	refreshes chan cache.Refresh
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{
+		lookups:   make(map[cache.LookupResult]int),
+		refreshes: make(chan cache.Refresh, 16),
+	}
+}
+
+func (m *recordingMetrics) RecordLookup(_ context.Context, lookup cache.Lookup) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lookups[lookup.Result]++
+}
+
+func (m *recordingMetrics) RecordRefresh(_ context.Context, refresh cache.Refresh) {
+	m.refreshes <- refresh
+}
+
+func (m *recordingMetrics) count(result cache.LookupResult) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lookups[result]
+}
+
+func newCache(clock *fakeClock, metrics cache.Metrics, opts ...cache.Option) *cache.Cache[string, int] {
+	store := cache.NewLRU[string, int](16, cache.WithLRUClock(clock.Now))
+	opts = append([]cache.Option{cache.WithClock(clock.Now), cache.WithMetrics(metrics)}, opts...)
+	return cache.New[string, int]("test", store, opts...)
+}
+
+func value(v int) func(context.Context) (int, error) {
+	return func(context.Context) (int, error) { return v, nil }
+}
+
+func failing(err error) func(context.Context) (int, error) {
+	return func(context.Context) (int, error) { return 0, err }
+}
+
+func TestCache_HitAndMiss(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	metrics := newRecordingMetrics()
+	c := newCache(clock, metrics, cache.WithTTL(time.Minute))
+
+	v, err := cache.Execute(context.Background(), c, "key", value(1))
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+
+	v, err = cache.Execute(context.Background(), c, "key", value(2))
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+
+	clock.Advance(time.Minute)
+	v, err = cache.Execute(context.Background(), c, "key", value(3))
+	require.NoError(t, err)
+	require.Equal(t, 3, v)
+
+	require.NoError(t, c.Invalidate(context.Background(), "key"))
+	v, _ = cache.Execute(context.Background(), c, "key", value(4))
+	require.Equal(t, 4, v)
+
+	require.Equal(t, 1, metrics.count(cache.LookupHit))
+	require.Equal(t, 3, metrics.count(cache.LookupMiss))
+}
+
+func TestCache_RefreshesSoftExpiredEntries(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	metrics := newRecordingMetrics()
+	c := newCache(clock, metrics, cache.WithTTL(time.Minute), cache.WithSoftTTL(30*time.Second))
+
+	_, err := cache.Execute(context.Background(), c, "key", value(1))
+	require.NoError(t, err)
+
+	clock.Advance(45 * time.Second)
+
+	// the caller's context being cancelled does not stop the refresh
+	ctx, cancel := context.WithCancel(context.Background())
+	v, err := cache.Execute(ctx, c, "key", value(2))
+	cancel()
+	require.NoError(t, err)
+	require.Equal(t, 1, v, "soft-expired entry is served while refreshing")
+
+	refresh := <-metrics.refreshes
+	require.NoError(t, refresh.Error)
+
+	v, _ = cache.Execute(context.Background(), c, "key", value(3))
+	require.Equal(t, 2, v)
+	require.Equal(t, 2, metrics.count(cache.LookupHit))
+}
+
+func TestCache_ServesStaleWhenUnavailable(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	metrics := newRecordingMetrics()
+	c := newCache(clock, metrics, cache.WithTTL(time.Minute), cache.WithStaleTTL(time.Hour))
+
+	_, err := cache.Execute(context.Background(), c, "key", value(1))
+	require.NoError(t, err)
+
+	clock.Advance(2 * time.Minute)
+
+	// errors that do not mean the dependency is unavailable are returned
+	_, err = cache.Execute(context.Background(), c, "key", failing(errUnavailable))
+	require.ErrorIs(t, err, errUnavailable)
+
+	v, err := cache.Execute(context.Background(), c, "key", failing(circuitbreaker.ErrOpenState))
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+
+	v, err = cache.Execute(context.Background(), c, "key", failing(&retry.RetryError{}))
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+
+	require.Equal(t, 2, metrics.count(cache.LookupStale))
+
+	clock.Advance(time.Hour)
+	_, err = cache.Execute(context.Background(), c, "key", failing(circuitbreaker.ErrOpenState))
+	require.ErrorIs(t, err, circuitbreaker.ErrOpenState)
+}
+
+func TestCache_ServesStaleWithOpenCircuitBreaker(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := newCache(clock, newRecordingMetrics(), cache.WithTTL(time.Minute))
+
+	cb := circuitbreaker.New(
+		"cache",
+		circuitbreaker.WithMinimumNumberOfCalls(2),
+		circuitbreaker.WithFailureRateThreshold(50),
+	)
+	policy := retry.MustNewPolicy(
+		"cache", retry.WithMaxAttempts(2), retry.WithBackoff(backoff.NewFixed(time.Millisecond)),
+	)
+
+	healthy := true
+	fetch := func(ctx context.Context) (int, error) {
+		return retry.ExecuteWithCircuit(
+			ctx, policy, cb, func(context.Context) (int, error) {
+				if healthy {
+					return 42, nil
+				}
+				return 0, errUnavailable
+			},
+		)
+	}
+
+	v, err := cache.Execute(context.Background(), c, "key", fetch)
+	require.NoError(t, err)
+	require.Equal(t, 42, v)
+
+	healthy = false
+	clock.Advance(2 * time.Minute)
+
+	for i := 0; i < 3; i++ {
+		v, err = cache.Execute(context.Background(), c, "key", fetch)
+		require.NoError(t, err)
+		require.Equal(t, 42, v)
+	}
+
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	lru := cache.NewLRU[string, int](2, cache.WithLRUClock(clock.Now))
+
+	require.NoError(t, lru.Set(ctx, "a", cache.Entry[int]{Value: 1}, time.Minute))
+	require.NoError(t, lru.Set(ctx, "b", cache.Entry[int]{Value: 2}, time.Minute))
+
+	_, ok, _ := lru.Get(ctx, "a")
+	require.True(t, ok)
+
+	require.NoError(t, lru.Set(ctx, "c", cache.Entry[int]{Value: 3}, time.Second))
+	_, ok, _ = lru.Get(ctx, "b")
+	require.False(t, ok)
+	require.Equal(t, 2, lru.Len())
+
+	clock.Advance(time.Second)
+	_, ok, _ = lru.Get(ctx, "c")
+	require.False(t, ok)
+
+	entry, ok, _ := lru.Get(ctx, "a")
+	require.True(t, ok)
+	require.Equal(t, 1, entry.Value)
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+	"github.com/hugolhafner/dskit/retry"
+)
+
+type Config struct {
+	Metrics Metrics
+
+	// TTL is how long an entry is fresh, expired entries are loaded again on the next call
+	TTL time.Duration
+
+	// SoftTTL is the age after which a fresh entry is still served but refreshed in the
+	// background. Zero or a value of at least TTL disables background refresh.
+	SoftTTL time.Duration
+
+	// StaleTTL is how long after TTL an entry is kept to be served when loading fails
+	StaleTTL time.Duration
+
+	// ServeStalePredicate decides whether a load error allows serving a stale entry
+	// instead of returning the error
+	ServeStalePredicate func(error) bool
+
+	// Now returns the current time, it can be replaced for deterministic tests
+	Now func() time.Time
+}
+
+type Option func(*Config)
+
+func defaultConfig() Config {
+	return Config{
+		TTL:                 time.Minute,
+		StaleTTL:            10 * time.Minute,
+		ServeStalePredicate: IsUnavailableError,
+		Now:                 time.Now,
+	}
+}
+
+// IsUnavailableError reports whether err means the dependency could not be reached: the call
+// was rejected by an open circuit breaker or a retry policy gave up on it
+func IsUnavailableError(err error) bool {
+	if circuitbreaker.IsCallNotPermittedError(err) {
+		return true
+	}
+
+	var retryError *retry.RetryError
+	return errors.As(err, &retryError)
+}
+
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Config) {
+		c.Metrics = metrics
+	}
+}
+
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.TTL = ttl
+	}
+}
+
+func WithSoftTTL(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.SoftTTL = ttl
+	}
+}
+
+func WithStaleTTL(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.StaleTTL = ttl
+	}
+}
+
+func WithServeStalePredicate(predicate func(error) bool) Option {
+	return func(c *Config) {
+		c.ServeStalePredicate = predicate
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(c *Config) {
+		c.Now = now
+	}
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Execute returns the cached value for key while it is fresh, otherwise it calls fn and caches
+// its result. Entries older than SoftTTL are served and refreshed in the background.
+//
+// When fn fails with an error accepted by ServeStalePredicate, by default an open circuit
+// breaker or an exhausted retry policy, an expired entry still within StaleTTL is served
+// instead of the error.
+//
+// Store read errors are treated as misses and failed writes are ignored, so the store never
+// fails a call on its own.
+func Execute[K comparable, T any](ctx context.Context, c *Cache[K, T], key K, fn func(context.Context) (T, error)) (T, error) {
+	entry, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		ok = false
+	}
+
+	var age time.Duration
+	if ok {
+		age = c.config.Now().Sub(entry.StoredAt)
+		if age < c.config.TTL {
+			if c.softExpired(age) {
+				c.refresh(ctx, key, fn)
+			}
+
+			c.recordLookup(ctx, LookupHit)
+			return entry.Value, nil
+		}
+	}
+
+	result, err := fn(ctx)
+	if err == nil {
+		c.set(ctx, key, result)
+		c.recordLookup(ctx, LookupMiss)
+		return result, nil
+	}
+
+	if ok && age < c.config.TTL+c.config.StaleTTL && c.config.ServeStalePredicate(err) {
+		c.recordLookup(ctx, LookupStale)
+		return entry.Value, nil
+	}
+
+	c.recordLookup(ctx, LookupMiss)
+	return result, err
+}
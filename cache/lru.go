@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+var _ Store[string, any] = (*LRU[string, any])(nil)
+
+type lruItem[K comparable, T any] struct {
+	key       K
+	entry     Entry[T]
+	expiresAt time.Time
+}
+
+// LRU is an in-memory Store that holds at most capacity entries, evicting the least recently
+// used entry when full. Entries expire once their ttl has passed.
+type LRU[K comparable, T any] struct {
+	capacity int
+	now      func() time.Time
+
+	mu    sync.Mutex
+	items map[K]*list.Element
+	order *list.List
+}
+
+type LRUOption func(*lruConfig)
+
+type lruConfig struct {
+	now func() time.Time
+}
+
+// WithLRUClock replaces the clock used to expire entries
+func WithLRUClock(now func() time.Time) LRUOption {
+	return func(c *lruConfig) {
+		c.now = now
+	}
+}
+
+// NewLRU creates an LRU store, a capacity below 1 is treated as 1
+func NewLRU[K comparable, T any](capacity int, opts ...LRUOption) *LRU[K, T] {
+	config := lruConfig{now: time.Now}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &LRU[K, T]{
+		capacity: capacity,
+		now:      config.now,
+		items:    make(map[K]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+func (l *LRU[K, T]) Get(_ context.Context, key K) (Entry[T], bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return Entry[T]{}, false, nil
+	}
+
+	item := el.Value.(*lruItem[K, T])
+	if !l.now().Before(item.expiresAt) {
+		l.removeUnsafe(el)
+		return Entry[T]{}, false, nil
+	}
+
+	l.order.MoveToFront(el)
+	return item.entry, true, nil
+}
+
+func (l *LRU[K, T]) Set(_ context.Context, key K, entry Entry[T], ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expiresAt := l.now().Add(ttl)
+
+	if el, ok := l.items[key]; ok {
+		item := el.Value.(*lruItem[K, T])
+		item.entry = entry
+		item.expiresAt = expiresAt
+		l.order.MoveToFront(el)
+		return nil
+	}
+
+	l.items[key] = l.order.PushFront(
+		&lruItem[K, T]{
+			key:       key,
+			entry:     entry,
+			expiresAt: expiresAt,
+		},
+	)
+
+	for l.order.Len() > l.capacity {
+		l.removeUnsafe(l.order.Back())
+	}
+
+	return nil
+}
+
+func (l *LRU[K, T]) Delete(_ context.Context, key K) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.items[key]; ok {
+		l.removeUnsafe(el)
+	}
+
+	return nil
+}
+
+// Len returns the number of entries held, including expired entries not yet removed
+func (l *LRU[K, T]) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
+
+func (l *LRU[K, T]) removeUnsafe(el *list.Element) {
+	item := l.order.Remove(el).(*lruItem[K, T])
+	delete(l.items, item.key)
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+var _ Metrics = (*NoopMetrics)(nil)
+
+var _globalMetrics atomic.Pointer[Metrics]
+
+type LookupResult int
+
+const (
+	// LookupHit means a fresh entry was served
+	LookupHit LookupResult = iota
+	// LookupMiss means there was no fresh entry and the value was loaded
+	LookupMiss
+	// LookupStale means loading failed and an expired entry was served instead
+	LookupStale
+)
+
+func (r LookupResult) String() string {
+	switch r {
+	case LookupHit:
+		return "hit"
+	case LookupMiss:
+		return "miss"
+	case LookupStale:
+		return "stale"
+	default:
+		return "unknown"
+	}
+}
+
+// Lookup represents a single call to Execute
+type Lookup struct {
+	Name   string
+	Result LookupResult
+}
+
+// Refresh represents a background refresh of a soft-expired entry
+type Refresh struct {
+	Name     string
+	Duration time.Duration
+	Error    error
+}
+
+// Metrics defines the interface for cache instrumentation
+type Metrics interface {
+	// RecordLookup records how a call to Execute was served
+	RecordLookup(ctx context.Context, lookup Lookup)
+
+	// RecordRefresh records a completed background refresh
+	RecordRefresh(ctx context.Context, refresh Refresh)
+}
+
+// NoopMetrics is a no-operation implementation of the Metrics interface
+type NoopMetrics struct{}
+
+func (n *NoopMetrics) RecordLookup(_ context.Context, _ Lookup) {
+	// No-op
+}
+
+func (n *NoopMetrics) RecordRefresh(_ context.Context, _ Refresh) {
+	// No-op
+}
+
+// SetGlobalMetrics sets the global Metrics implementation
+func SetGlobalMetrics(m Metrics) {
+	if m == nil {
+		m = &NoopMetrics{}
+	}
+
+	_globalMetrics.Store(&m)
+}
+
+// GetGlobalMetrics returns the global Metrics implementation
+func GetGlobalMetrics() Metrics {
+	m := _globalMetrics.Load()
+	if m == nil {
+		return &NoopMetrics{}
+	}
+	return *m
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metrics:
+// cache_lookups_total (Counter) - Total number of cache lookups
+// * name (string) - The name of the cache
+// * result (string) - How the lookup was served ("hit", "miss", "stale")
+//
+// cache_refreshes_total (Counter) - Total number of background refreshes
+// * name (string) - The name of the cache
+// * status (string) - The status of the refresh ("success", "failure")
+//
+// cache_refreshes_duration_milliseconds (Histogram) - Duration of background refreshes in milliseconds
+// * name (string) - The name of the cache
+// * status (string) - The status of the refresh
+
+const (
+	instrumentationName    = "github.com/hugolhafner/dskit/cache"
+	instrumentationVersion = "v0.1.0" // x-release-please
+)
+
+const (
+	unitLookup       = "{lookup}"
+	unitRefresh      = "{refresh}"
+	unitMilliseconds = "ms"
+)
+
+var _ Metrics = (*OTelMetrics)(nil)
+
+type OTelMetrics struct {
+	lookupsTotal metric.Int64Counter
+
+	refreshesTotal    metric.Int64Counter
+	refreshesDuration metric.Float64Histogram
+}
+
+type OTelConfig struct {
+	MeterProvider metric.MeterProvider
+	MetricPrefix  string
+}
+
+type OTelOption func(*OTelConfig)
+
+func WithMeterProvider(meterProvider metric.MeterProvider) OTelOption {
+	return func(cfg *OTelConfig) {
+		cfg.MeterProvider = meterProvider
+	}
+}
+
+func WithMetricPrefix(prefix string) OTelOption {
+	return func(cfg *OTelConfig) {
+		cfg.MetricPrefix = prefix
+	}
+}
+
+func NewOTelMetrics(opts ...OTelOption) (*OTelMetrics, error) {
+	cfg := &OTelConfig{
+		MeterProvider: otel.GetMeterProvider(),
+		MetricPrefix:  "cache_",
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	meter := cfg.MeterProvider.Meter(instrumentationName, metric.WithInstrumentationVersion(instrumentationVersion))
+
+	lookupsTotal, err := meter.Int64Counter(
+		cfg.MetricPrefix+"lookups_total",
+		metric.WithDescription("Total number of cache lookups"),
+		metric.WithUnit(unitLookup),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lookups_total counter: %w", err)
+	}
+
+	refreshesTotal, err := meter.Int64Counter(
+		cfg.MetricPrefix+"refreshes_total",
+		metric.WithDescription("Total number of background refreshes"),
+		metric.WithUnit(unitRefresh),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refreshes_total counter: %w", err)
+	}
+
+	refreshesDuration, err := meter.Float64Histogram(
+		cfg.MetricPrefix+"refreshes_duration_milliseconds",
+		metric.WithDescription("Duration of background refreshes in milliseconds"),
+		metric.WithUnit(unitMilliseconds),
+		metric.WithExplicitBucketBoundaries(0, 1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refreshes_duration_milliseconds histogram: %w", err)
+	}
+
+	return &OTelMetrics{
+		lookupsTotal:      lookupsTotal,
+		refreshesTotal:    refreshesTotal,
+		refreshesDuration: refreshesDuration,
+	}, nil
+}
+
+func MustNewOTelMetrics(opts ...OTelOption) *OTelMetrics {
+	m, err := NewOTelMetrics(opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+func (m *OTelMetrics) RecordLookup(ctx context.Context, lookup Lookup) {
+	m.lookupsTotal.Add(
+		ctx, 1, metric.WithAttributes(
+			attribute.String("name", lookup.Name),
+			attribute.String("result", lookup.Result.String()),
+		),
+	)
+}
+
+func (m *OTelMetrics) RecordRefresh(ctx context.Context, refresh Refresh) {
+	status := "success"
+	if refresh.Error != nil {
+		status = "failure"
+	}
+
+	attrs := metric.WithAttributes(
+		attribute.String("name", refresh.Name),
+		attribute.String("status", status),
+	)
+
+	m.refreshesTotal.Add(ctx, 1, attrs)
+	m.refreshesDuration.Record(ctx, float64(refresh.Duration.Milliseconds()), attrs)
+}
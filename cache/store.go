@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Entry is a cached value together with the time it was stored
+type Entry[T any] struct {
+	Value    T
+	StoredAt time.Time
+}
+
+// Store holds cache entries. Implementations must be safe for concurrent use.
+//
+// The ttl passed to Set is how long the entry must be kept at least, which includes the
+// period it may be served stale. The cache decides whether an entry is fresh from StoredAt.
+type Store[K comparable, T any] interface {
+	// Get returns the entry for key, ok is false if there is none or it has expired
+	Get(ctx context.Context, key K) (entry Entry[T], ok bool, err error)
+
+	// Set stores the entry for key for at least ttl
+	Set(ctx context.Context, key K, entry Entry[T], ttl time.Duration) error
+
+	// Delete removes the entry for key
+	Delete(ctx context.Context, key K) error
+}
@@ -40,20 +40,55 @@ func IsCallNotPermittedError(err error) bool {
 	return errors.Is(err, ErrOpenState) || errors.Is(err, ErrHalfOpenState)
 }
 
+// Guard permits calls and records their outcome, it is all Execute needs from a circuit breaker
+type Guard interface {
+	// TryAcquirePermission permits a call recorded through the returned Permit, or returns
+	// an error matching IsCallNotPermittedError when the call is not permitted
+	TryAcquirePermission() (Permit, error)
+}
+
+// ContextGuard is a Guard that takes the context of the call it permits, Execute prefers it over
+// TryAcquirePermission
+type ContextGuard interface {
+	Guard
+
+	// TryAcquirePermissionContext is TryAcquirePermission for a call made with ctx, its
+	// ContextWithSlowCallDurationThreshold and cancellation apply when the call is recorded
+	TryAcquirePermissionContext(ctx context.Context) (Permit, error)
+}
+
+// CircuitBreaker is implemented by the circuit breaker returned from New. Code that only
+// protects calls should depend on Guard, which is simpler to fake or decorate.
 type CircuitBreaker interface {
+	ContextGuard
+
 	Name() string
 	State() State
 
-	before() error
-	after(result any, err error, duration time.Duration)
+	// Metrics returns a point-in-time snapshot of the circuit breaker's internal state
+	Metrics() MetricsSnapshot
+
+	// Snapshot returns the state and window contents of the circuit breaker for persisting
+	Snapshot() Snapshot
+
+	// Restore resumes the state and window contents from a snapshot of a circuit breaker with the same name
+	Restore(snapshot Snapshot) error
+
+	// Close stops the timers of automatic transitions out of the open and half-open states,
+	// afterwards the circuit breaker only transitions when a call arrives. It waits for a
+	// running sync with the StateStore to complete.
+	Close() error
 }
 
 var _ CircuitBreaker = (*circuitBreakerImpl)(nil)
 
 type circuitBreakerImpl struct {
-	name   string
-	window Window
-	config Config
+	name string
+
+	// window is safe for concurrent use, see newConcurrentWindow
+	window     Window
+	config     Config
+	classifier Classifier
 
 	metrics Metrics
 
@@ -63,6 +98,35 @@ type circuitBreakerImpl struct {
 
 	halfOpenCompletedLeases int
 	halfOpenLeases          int
+
+	consecutiveFailures  int
+	consecutiveSuccesses int
+
+	// openAttempts is the number of consecutive openings since the circuit breaker last closed
+	openAttempts uint
+	openWait     time.Duration
+
+	// halfOpenEpisode identifies the current half-open state, so leases granted in an earlier
+	// one are not returned to it
+	halfOpenEpisode uint64
+
+	// stateTimer ends the open or half-open state when it is due, stateTimerGeneration lets a
+	// timer that fired after being replaced recognize it is stale
+	stateTimer           Timer
+	stateTimerGeneration uint64
+	closed               bool
+
+	notPermittedCalls int64
+
+	// latency derives the adaptive slow call duration threshold, nil when it is not configured
+	latency *latencyTracker
+
+	// syncMu is held while syncing with the StateStore, the fields below are guarded by mu
+	syncMu        sync.Mutex
+	lastStateSync time.Time
+	sharedVersion uint64
+	stateDirty    bool
+	transitions   uint64
 }
 
 func New(name string, opts ...Option) CircuitBreaker {
@@ -71,17 +135,37 @@ func New(name string, opts ...Option) CircuitBreaker {
 		opt(&config)
 	}
 
+	if config.TripStrategy == nil {
+		strategy := NewRateTripStrategy(
+			config.MinimumNumberOfCalls, config.FailureRateThreshold, config.SlowCallRateThreshold,
+		)
+		strategy.CategoryRateThresholds = config.CategoryRateThresholds
+		config.TripStrategy = strategy
+	}
+
+	if config.StateStore != nil && config.InstanceID == "" {
+		config.InstanceID = defaultInstanceID()
+	}
+
+	if config.Clock == nil {
+		config.Clock = SystemClock()
+	}
+
 	initialState := StateClosed
 	if config.MetricsOnlyMode {
 		initialState = StateMetricsOnly
 	}
 
 	cb := &circuitBreakerImpl{
-		name:    name,
-		config:  config,
-		state:   StateOpen,
-		window:  config.Window,
-		metrics: config.Metrics,
+		name:       name,
+		config:     config,
+		classifier: config.Classifier(),
+		state:      StateOpen,
+		window:     newConcurrentWindow(config.Window, config.Clock),
+		metrics:    config.Metrics,
+	}
+	if config.AdaptiveSlowCallThreshold != nil {
+		cb.latency = newLatencyTracker(*config.AdaptiveSlowCallThreshold, config.Clock)
 	}
 	cb.setStateUnsafe(initialState)
 
@@ -98,6 +182,34 @@ func (cb *circuitBreakerImpl) State() State {
 	return cb.state
 }
 
+func (cb *circuitBreakerImpl) Metrics() MetricsSnapshot {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	rates := cb.window.CallRates()
+
+	snapshot := MetricsSnapshot{
+		Name:                cb.name,
+		State:               cb.state,
+		TransitionTime:      cb.transitionTime,
+		TimeSinceTransition: cb.config.Clock.Now().Sub(cb.transitionTime),
+		BufferedCalls:       rates.TotalCalls,
+		FailureRate:         rates.FailureRate,
+		SlowCallRate:        rates.SlowCallRate,
+		FailureRatePercent:  rates.FailureRatePercent,
+		SlowCallRatePercent: rates.SlowCallRatePercent,
+		NotPermittedCalls:   cb.notPermittedCalls,
+
+		WaitDurationInOpenState: cb.openWait,
+	}
+
+	if cb.state == StateHalfOpen {
+		snapshot.HalfOpenPermitsAvailable = cb.halfOpenLeases
+	}
+
+	return snapshot
+}
+
 func (cb *circuitBreakerImpl) setStateUnsafe(state State) {
 	if cb.state == state {
 		return
@@ -105,14 +217,29 @@ func (cb *circuitBreakerImpl) setStateUnsafe(state State) {
 
 	oldState := cb.state
 
-	if state == StateHalfOpen {
+	var openWait time.Duration
+	switch state {
+	case StateHalfOpen:
 		cb.halfOpenLeases = cb.config.PermittedNumberOfCallsInHalfOpenState
 		cb.halfOpenCompletedLeases = 0
+		cb.halfOpenEpisode++
+	case StateOpen:
+		cb.openAttempts++
+		cb.openWait = cb.waitDurationInOpenState(cb.openAttempts)
+		openWait = cb.openWait
+	case StateClosed:
+		cb.openAttempts = 0
+	default:
 	}
 
 	cb.state = state
-	cb.transitionTime = time.Now()
+	cb.transitionTime = cb.config.Clock.Now()
 	cb.window.Reset()
+	cb.consecutiveFailures = 0
+	cb.consecutiveSuccesses = 0
+
+	cb.transitions++
+	cb.stateDirty = true
 
 	cb.metricsReporter().RecordStateTransition(
 		context.Background(), StateTransition{
@@ -120,24 +247,150 @@ func (cb *circuitBreakerImpl) setStateUnsafe(state State) {
 			FromState: oldState,
 			ToState:   state,
 			Timestamp: cb.transitionTime,
+
+			WaitDurationInOpenState: openWait,
+		},
+	)
+
+	cb.scheduleStateTimerUnsafe()
+}
+
+// scheduleStateTimerUnsafe replaces the pending automatic transition out of the open or half-open
+// state, it has to be called again whenever the transition time changes
+func (cb *circuitBreakerImpl) scheduleStateTimerUnsafe() {
+	if cb.stateTimer != nil {
+		cb.stateTimer.Stop()
+		cb.stateTimer = nil
+	}
+	cb.stateTimerGeneration++
+
+	if cb.closed {
+		return
+	}
+
+	var deadline time.Duration
+	switch {
+	case cb.state == StateOpen && cb.config.AutomaticTransitionFromOpenToHalfOpen:
+		deadline = cb.openWait
+	case cb.state == StateHalfOpen && cb.config.MaxWaitDurationInHalfOpenState > 0:
+		deadline = cb.config.MaxWaitDurationInHalfOpenState
+	default:
+		return
+	}
+
+	generation := cb.stateTimerGeneration
+	state := cb.state
+	wait := deadline - cb.config.Clock.Now().Sub(cb.transitionTime)
+
+	cb.stateTimer = cb.config.Clock.AfterFunc(
+		max(wait, 0), func() {
+			cb.mu.Lock()
+			defer cb.mu.Unlock()
+
+			if cb.stateTimerGeneration == generation && cb.state == state {
+				cb.stateTimer = nil
+				cb.expireStateUnsafe()
+				cb.requestSyncUnsafe()
+			}
 		},
 	)
 }
 
-func (cb *circuitBreakerImpl) before() error {
+// expireStateUnsafe leaves the open state for half-open, or the half-open state after its
+// maximum wait. A half-open state that timed out is decided on the calls completed so far,
+// and opens again if none completed.
+func (cb *circuitBreakerImpl) expireStateUnsafe() {
+	switch cb.state {
+	case StateOpen:
+		cb.setStateUnsafe(StateHalfOpen)
+	case StateHalfOpen:
+		if cb.halfOpenCompletedLeases == 0 {
+			cb.setStateUnsafe(StateOpen)
+			return
+		}
+
+		evaluation := cb.tripEvaluationUnsafe(cb.window.CallRates())
+		evaluation.HalfOpenPermittedCalls = cb.halfOpenCompletedLeases
+
+		if cb.config.TripStrategy.Evaluate(evaluation) == StateClosed {
+			cb.setStateUnsafe(StateClosed)
+		} else {
+			cb.setStateUnsafe(StateOpen)
+		}
+	default:
+	}
+}
+
+func (cb *circuitBreakerImpl) Close() error {
+	cb.mu.Lock()
+	cb.closed = true
+	cb.scheduleStateTimerUnsafe()
+	cb.mu.Unlock()
+
+	cb.waitForSync()
+	return nil
+}
+
+func (cb *circuitBreakerImpl) waitDurationInOpenState(attempt uint) time.Duration {
+	if cb.config.WaitDurationInOpenStateBackoff != nil {
+		return cb.config.WaitDurationInOpenStateBackoff.Next(attempt)
+	}
+
+	return cb.config.WaitDurationInOpenState
+}
+
+// halfOpenLease is a call permitted in the half-open state. Its permit is returned when the
+// call is abandoned, so callers that give up do not use up the permits and leave the circuit
+// breaker stuck in the half-open state.
+type halfOpenLease struct {
+	episode uint64
+
+	// reclaimed and completed are guarded by the circuit breaker mutex
+	reclaimed bool
+	completed bool
+}
+
+func (cb *circuitBreakerImpl) reclaimLease(lease *halfOpenLease) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.reclaimLeaseUnsafe(lease)
+}
+
+func (cb *circuitBreakerImpl) reclaimLeaseUnsafe(lease *halfOpenLease) {
+	if lease.completed || lease.reclaimed {
+		return
+	}
+
+	lease.reclaimed = true
+	if cb.state == StateHalfOpen && cb.halfOpenEpisode == lease.episode {
+		cb.halfOpenLeases++
+	}
+}
+
+func (cb *circuitBreakerImpl) before() (*halfOpenLease, error) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
+	cb.requestSyncUnsafe()
+
 	if cb.state == StateMetricsOnly {
-		return nil
+		return nil, nil
 	}
 
-	if cb.state == StateOpen && time.Since(cb.transitionTime) >= cb.config.WaitDurationInOpenState {
-		cb.setStateUnsafe(StateHalfOpen)
+	if cb.state == StateHalfOpen && cb.config.MaxWaitDurationInHalfOpenState > 0 &&
+		cb.timeInStateUnsafe() >= cb.config.MaxWaitDurationInHalfOpenState {
+		cb.expireStateUnsafe()
+	}
+
+	// measured again, a half-open state that just expired starts a new wait in the open state
+	if cb.state == StateOpen && cb.timeInStateUnsafe() >= cb.openWait {
+		cb.expireStateUnsafe()
 	}
 
 	switch cb.state {
 	case StateOpen:
+		cb.notPermittedCalls++
 		cb.metricsReporter().RecordCallRejection(
 			context.Background(), CallRejection{
 				Name:  cb.name,
@@ -145,9 +398,10 @@ func (cb *circuitBreakerImpl) before() error {
 				Error: ErrOpenState,
 			},
 		)
-		return ErrOpenState
+		return nil, ErrOpenState
 	case StateHalfOpen:
 		if cb.halfOpenLeases <= 0 {
+			cb.notPermittedCalls++
 			cb.metricsReporter().RecordCallRejection(
 				context.Background(), CallRejection{
 					Name:  cb.name,
@@ -156,44 +410,100 @@ func (cb *circuitBreakerImpl) before() error {
 				},
 			)
 
-			return ErrHalfOpenState
+			return nil, ErrHalfOpenState
 		}
 		cb.halfOpenLeases--
+
+		return &halfOpenLease{episode: cb.halfOpenEpisode}, nil
 	default:
 	}
 
-	return nil
+	return nil, nil
 }
 
-func (cb *circuitBreakerImpl) after(result any, err error, duration time.Duration) {
-	isFailure := cb.shouldFailCall(result, err)
-	isSlow := duration >= cb.config.SlowCallDurationThreshold
+func (cb *circuitBreakerImpl) timeInStateUnsafe() time.Duration {
+	return cb.config.Clock.Now().Sub(cb.transitionTime)
+}
 
-	var outcome CallOutcome
-	if isFailure && isSlow {
-		outcome = OutcomeSlowFailure
-	} else if isFailure {
-		outcome = OutcomeFailure
-	} else if isSlow {
-		outcome = OutcomeSlowSuccess
-	} else {
-		outcome = OutcomeSuccess
+// after records a call, ctx is the context of the call or nil when it was not run through Execute
+func (cb *circuitBreakerImpl) after(
+	lease *halfOpenLease, ctx context.Context, result any, err error, duration time.Duration,
+) {
+	record := cb.classifyCall(ctx, result, err, duration)
+	if cb.latency != nil && !record.Outcome.IsIgnored() {
+		cb.latency.record(duration)
+	}
+
+	var rates CallRates
+	if lease == nil {
+		// the window is safe for concurrent use, so a call without a half-open lease is recorded
+		// and the rates are read without holding the lock
+		cb.window.Record(record)
+		rates = cb.window.CallRates()
 	}
 
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	cb.window.RecordOutcome(outcome)
+	// runs before the lock is released, publishing a transition caused by this call right away
+	defer cb.requestSyncUnsafe()
+
+	if lease != nil {
+		// read under the lock, so the call completing the half-open state sees every call before it
+		if !lease.reclaimed {
+			cb.window.Record(record)
+		}
+		rates = cb.window.CallRates()
+	}
+
+	if lease != nil && lease.reclaimed {
+		// the caller cancelled and the permit was handed to another call, this one no longer counts
+		cb.recordCallResultUnsafe(record.Outcome, err, duration, rates)
+		return
+	}
+
+	if record.Outcome.IsIgnored() {
+		// an ignored call tells nothing about the dependency, so its half-open permit is handed back
+		if lease != nil {
+			cb.reclaimLeaseUnsafe(lease)
+		}
+		cb.recordCallResultUnsafe(record.Outcome, err, duration, rates)
+		return
+	}
+
+	if lease != nil {
+		lease.completed = true
+	}
 
 	// MetricsOnly mode: record metrics but skip state transition evaluation
 	if cb.state != StateMetricsOnly {
-		if cb.state == StateHalfOpen && !IsCallNotPermittedError(err) {
+		if lease != nil && cb.state == StateHalfOpen && lease.episode == cb.halfOpenEpisode &&
+			!IsCallNotPermittedError(err) {
 			cb.halfOpenCompletedLeases++
 		}
 
-		cb.evaluateStateTransitionUnsafe()
+		if record.Outcome.IsFailure() {
+			cb.consecutiveFailures++
+			cb.consecutiveSuccesses = 0
+		} else {
+			cb.consecutiveSuccesses++
+			cb.consecutiveFailures = 0
+		}
+
+		transitions := cb.transitions
+		cb.evaluateStateTransitionUnsafe(rates)
+		if cb.transitions != transitions {
+			// the transition reset the window
+			rates = cb.window.CallRates()
+		}
 	}
 
+	cb.recordCallResultUnsafe(record.Outcome, err, duration, rates)
+}
+
+func (cb *circuitBreakerImpl) recordCallResultUnsafe(
+	outcome CallOutcome, err error, duration time.Duration, rates CallRates,
+) {
 	cb.metricsReporter().RecordCallResult(
 		context.Background(), CallResult{
 			Name:     cb.name,
@@ -203,71 +513,63 @@ func (cb *circuitBreakerImpl) after(result any, err error, duration time.Duratio
 		},
 	)
 
-	totalCalls, successRate, failureRate, slowRate := cb.window.CallRates()
-	cb.metricsReporter().RecordCallRates(
-		context.Background(), CallRates{
-			Name:         cb.name,
-			SuccessRate:  successRate,
-			FailureRate:  failureRate,
-			SlowCallRate: slowRate,
-			TotalCalls:   totalCalls,
-		},
-	)
+	rates.Name = cb.name
+	rates.SlowCallDurationThreshold = cb.slowCallDurationThreshold()
+	cb.metricsReporter().RecordCallRates(context.Background(), rates)
 }
 
-func (cb *circuitBreakerImpl) evaluateStateTransitionUnsafe() {
-	if cb.state == StateMetricsOnly {
-		return
+func (cb *circuitBreakerImpl) classifyCall(ctx context.Context, result any, err error, duration time.Duration) CallRecord {
+	if isCallerCancellation(ctx, err) {
+		return CallRecord{Outcome: OutcomeCancelled, Weight: 1}
 	}
 
-	switch cb.state {
-	case StateClosed:
-		if cb.window.Size() >= cb.config.MinimumNumberOfCalls && cb.areThresholdsExceededUnsafe() {
-			cb.setStateUnsafe(StateOpen)
+	threshold := cb.slowCallDurationThreshold()
+	if ctx != nil {
+		if callThreshold, ok := SlowCallDurationThresholdFromContext(ctx); ok {
+			threshold = callThreshold
 		}
-	case StateHalfOpen:
-		if cb.halfOpenCompletedLeases >= cb.config.PermittedNumberOfCallsInHalfOpenState {
-			if cb.areThresholdsExceededUnsafe() {
-				cb.setStateUnsafe(StateOpen)
-			} else {
-				cb.setStateUnsafe(StateClosed)
-			}
-		}
-	default:
 	}
-}
 
-func (cb *circuitBreakerImpl) areThresholdsExceededUnsafe() bool {
-	_, _, failureRate, slowRate := cb.window.CallRates()
-	return failureRate >= cb.config.FailureRateThreshold || slowRate >= cb.config.SlowCallRateThreshold
+	return cb.classifier.classifyCall(result, err, duration, threshold)
 }
 
-func (cb *circuitBreakerImpl) shouldFailCall(result any, err error) bool {
-	if err != nil {
-		if cb.config.FailOnErrorPredicate != nil && cb.config.FailOnErrorPredicate(err) {
-			return true
-		}
-
-		for _, failErr := range cb.config.FailErrors {
-			if errors.Is(err, failErr) {
-				return true
-			}
+// slowCallDurationThreshold is the threshold of calls without one of their own: the adaptive
+// threshold once it is derived, SlowCallDurationThreshold otherwise
+func (cb *circuitBreakerImpl) slowCallDurationThreshold() time.Duration {
+	if cb.latency != nil {
+		if threshold := cb.latency.threshold(); threshold > 0 {
+			return threshold
 		}
+	}
 
-		for _, ignoreErr := range cb.config.IgnoreErrors {
-			if errors.Is(err, ignoreErr) {
-				return false
-			}
-		}
+	return cb.config.SlowCallDurationThreshold
+}
 
-		return true
+// evaluateStateTransitionUnsafe decides the next state from rates, the rates of the window
+func (cb *circuitBreakerImpl) evaluateStateTransitionUnsafe(rates CallRates) {
+	if cb.state != StateClosed && cb.state != StateHalfOpen {
+		return
 	}
 
-	if cb.config.FailOnResultPredicate != nil {
-		return cb.config.FailOnResultPredicate(result)
+	next := cb.config.TripStrategy.Evaluate(cb.tripEvaluationUnsafe(rates))
+
+	// a closed circuit breaker can only open, a half-open one can open or close
+	if next == StateOpen || (cb.state == StateHalfOpen && next == StateClosed) {
+		cb.setStateUnsafe(next)
 	}
+}
 
-	return false
+func (cb *circuitBreakerImpl) tripEvaluationUnsafe(rates CallRates) TripEvaluation {
+	rates.SlowCallDurationThreshold = cb.slowCallDurationThreshold()
+
+	return TripEvaluation{
+		State:                  cb.state,
+		CallRates:              rates,
+		ConsecutiveFailures:    cb.consecutiveFailures,
+		ConsecutiveSuccesses:   cb.consecutiveSuccesses,
+		HalfOpenCompletedCalls: cb.halfOpenCompletedLeases,
+		HalfOpenPermittedCalls: cb.config.PermittedNumberOfCallsInHalfOpenState,
+	}
 }
 
 func (cb *circuitBreakerImpl) metricsReporter() Metrics {
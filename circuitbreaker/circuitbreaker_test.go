@@ -0,0 +1,145 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/backoff"
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+var errDependency = errors.New("dependency failed")
+
+func failingCall(_ context.Context) error {
+	return errDependency
+}
+
+func TestCircuitBreaker_OpensAfterFailureThreshold(t *testing.T) {
+	metrics := circuitbreaker.NewInMemoryMetrics()
+	cb := circuitbreaker.New(
+		"test",
+		circuitbreaker.WithMetrics(metrics),
+		circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)),
+		circuitbreaker.WithMinimumNumberOfCalls(4),
+		circuitbreaker.WithFailureRateThreshold(50),
+	)
+
+	for i := 0; i < 4; i++ {
+		err := circuitbreaker.Do(context.Background(), cb, failingCall)
+		require.ErrorIs(t, err, errDependency)
+	}
+
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+
+	err := circuitbreaker.Do(context.Background(), cb, failingCall)
+	require.ErrorIs(t, err, circuitbreaker.ErrOpenState)
+
+	snapshot := cb.Metrics()
+	require.Equal(t, "test", snapshot.Name)
+	require.Equal(t, circuitbreaker.StateOpen, snapshot.State)
+	require.Equal(t, int64(1), snapshot.NotPermittedCalls)
+	require.Zero(t, snapshot.BufferedCalls)
+	require.Zero(t, snapshot.HalfOpenPermitsAvailable)
+
+	stats, ok := metrics.GetMetrics("test")
+	require.True(t, ok)
+	require.Equal(t, circuitbreaker.StateOpen, stats.State)
+	require.Equal(t, int64(4), stats.Calls[circuitbreaker.OutcomeFailure])
+	require.Equal(t, int64(1), stats.Rejections[circuitbreaker.StateOpen])
+}
+
+func TestCircuitBreaker_MetricsSnapshotRates(t *testing.T) {
+	cb := circuitbreaker.New(
+		"rates",
+		circuitbreaker.WithMetrics(&circuitbreaker.NoopMetrics{}),
+		circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)),
+	)
+
+	require.NoError(t, circuitbreaker.Do(context.Background(), cb, func(context.Context) error { return nil }))
+	require.Error(t, circuitbreaker.Do(context.Background(), cb, failingCall))
+
+	snapshot := cb.Metrics()
+	require.Equal(t, circuitbreaker.StateClosed, snapshot.State)
+	require.Equal(t, 2, snapshot.BufferedCalls)
+	require.InDelta(t, 0.5, snapshot.FailureRate, 0.001)
+	require.InDelta(t, 0.0, snapshot.SlowCallRate, 0.001)
+	require.InDelta(t, 50.0, snapshot.FailureRatePercent, 0.001)
+	require.InDelta(t, 0.0, snapshot.SlowCallRatePercent, 0.001)
+}
+
+func TestCircuitBreaker_RateThresholdsArePercentages(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		want      circuitbreaker.State
+	}{
+		{name: "below percentage", threshold: 30, want: circuitbreaker.StateClosed},
+		{name: "at percentage", threshold: 25, want: circuitbreaker.StateOpen},
+		// a threshold given as a ratio trips on almost any failure
+		{name: "ratio", threshold: 0.3, want: circuitbreaker.StateOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				cb := circuitbreaker.New(
+					"percentages",
+					circuitbreaker.WithMetrics(&circuitbreaker.NoopMetrics{}),
+					circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(4)),
+					circuitbreaker.WithMinimumNumberOfCalls(4),
+					circuitbreaker.WithFailureRateThreshold(tt.threshold),
+				)
+
+				for range 3 {
+					require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+				}
+				require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, failingCall), errDependency)
+
+				require.Equal(t, tt.want, cb.State())
+			},
+		)
+	}
+}
+
+func TestCircuitBreaker_OpenStateBackoff(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	metrics := circuitbreaker.NewInMemoryMetrics()
+	cb := circuitbreaker.New(
+		"backoff",
+		circuitbreaker.WithClock(clock),
+		circuitbreaker.WithMetrics(metrics),
+		circuitbreaker.WithTripStrategy(circuitbreaker.NewConsecutiveTripStrategy(1, 1)),
+		circuitbreaker.WithWaitDurationInOpenStateBackoff(
+			backoff.NewExponential(
+				backoff.WithInitialInterval(30*time.Millisecond),
+				backoff.WithMultiplier(2),
+				backoff.WithMaxInterval(time.Second),
+			),
+		),
+	)
+
+	_ = circuitbreaker.Do(context.Background(), cb, failingCall)
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+	require.Equal(t, 30*time.Millisecond, cb.Metrics().WaitDurationInOpenState)
+
+	// the half-open probe fails, so the next wait doubles
+	clock.Advance(30 * time.Millisecond)
+	require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, failingCall), errDependency)
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+	require.Equal(t, 60*time.Millisecond, cb.Metrics().WaitDurationInOpenState)
+
+	clock.Advance(59 * time.Millisecond)
+	require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, succeedingCall), circuitbreaker.ErrOpenState)
+
+	clock.Advance(time.Millisecond)
+	require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+	require.Equal(t, circuitbreaker.StateClosed, cb.State())
+
+	// closing resets the backoff
+	_ = circuitbreaker.Do(context.Background(), cb, failingCall)
+	require.Equal(t, 30*time.Millisecond, cb.Metrics().WaitDurationInOpenState)
+}
@@ -0,0 +1,218 @@
+// Package circuitbreakertest provides a scriptable circuit breaker for unit tests of code that
+// depends on circuitbreaker.CircuitBreaker or circuitbreaker.Guard.
+package circuitbreakertest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+var _ circuitbreaker.CircuitBreaker = (*Breaker)(nil)
+
+type CallKind int
+
+const (
+	// CallSuccess is a call recorded with Permit.OnSuccess
+	CallSuccess CallKind = iota
+	// CallError is a call recorded with Permit.OnError
+	CallError
+	// CallResult is a call recorded with Permit.OnResult
+	CallResult
+	// CallReleased is a permit given back with Permit.Release
+	CallReleased
+)
+
+func (k CallKind) String() string {
+	switch k {
+	case CallSuccess:
+		return "success"
+	case CallError:
+		return "error"
+	case CallResult:
+		return "result"
+	case CallReleased:
+		return "released"
+	default:
+		return "unknown"
+	}
+}
+
+// Call is a permitted call as it was recorded through its permit
+type Call struct {
+	Kind     CallKind
+	Result   any
+	Err      error
+	Duration time.Duration
+}
+
+// Breaker is a fake circuit breaker that never transitions on its own. Calls are permitted
+// unless the state is forced to open or a scripted rejection is pending, and every permitted
+// call is recorded.
+type Breaker struct {
+	name string
+
+	mu         sync.Mutex
+	state      circuitbreaker.State
+	script     []error
+	calls      []Call
+	rejections int64
+	closed     bool
+}
+
+// New creates a fake circuit breaker in the closed state
+func New(name string) *Breaker {
+	return &Breaker{
+		name:  name,
+		state: circuitbreaker.StateClosed,
+	}
+}
+
+// SetState forces the state, the open state rejects every call with circuitbreaker.ErrOpenState
+func (b *Breaker) SetState(state circuitbreaker.State) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = state
+}
+
+// Script queues the results of the next calls to TryAcquirePermission, a nil error permits
+// the call and any other error rejects it. Scripted results take precedence over the state.
+func (b *Breaker) Script(results ...error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.script = append(b.script, results...)
+}
+
+// Calls returns the permitted calls that were recorded or released
+func (b *Breaker) Calls() []Call {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	calls := make([]Call, len(b.calls))
+	copy(calls, b.calls)
+	return calls
+}
+
+// Rejections returns the number of calls that were not permitted
+func (b *Breaker) Rejections() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.rejections
+}
+
+// Closed reports whether Close was called
+func (b *Breaker) Closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.closed
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+func (b *Breaker) State() circuitbreaker.State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+func (b *Breaker) TryAcquirePermission() (circuitbreaker.Permit, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var err error
+	if len(b.script) > 0 {
+		err = b.script[0]
+		b.script = b.script[1:]
+	} else if b.state == circuitbreaker.StateOpen {
+		err = circuitbreaker.ErrOpenState
+	}
+
+	if err != nil {
+		b.rejections++
+		return nil, err
+	}
+
+	return &permit{breaker: b}, nil
+}
+
+// TryAcquirePermissionContext is TryAcquirePermission, the fake does not use ctx
+func (b *Breaker) TryAcquirePermissionContext(_ context.Context) (circuitbreaker.Permit, error) {
+	return b.TryAcquirePermission()
+}
+
+func (b *Breaker) Metrics() circuitbreaker.MetricsSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return circuitbreaker.MetricsSnapshot{
+		Name:              b.name,
+		State:             b.state,
+		BufferedCalls:     len(b.calls),
+		NotPermittedCalls: b.rejections,
+	}
+}
+
+func (b *Breaker) Snapshot() circuitbreaker.Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return circuitbreaker.Snapshot{
+		Name:  b.name,
+		State: b.state,
+	}
+}
+
+func (b *Breaker) Restore(snapshot circuitbreaker.Snapshot) error {
+	if snapshot.Name != b.name {
+		return fmt.Errorf("%w: got %q, want %q", circuitbreaker.ErrSnapshotNameMismatch, snapshot.Name, b.name)
+	}
+
+	b.SetState(snapshot.State)
+	return nil
+}
+
+func (b *Breaker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	return nil
+}
+
+func (b *Breaker) record(call Call) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.calls = append(b.calls, call)
+}
+
+type permit struct {
+	breaker *Breaker
+	once    sync.Once
+}
+
+func (p *permit) OnSuccess(duration time.Duration) {
+	p.once.Do(func() { p.breaker.record(Call{Kind: CallSuccess, Duration: duration}) })
+}
+
+func (p *permit) OnError(duration time.Duration, err error) {
+	p.once.Do(func() { p.breaker.record(Call{Kind: CallError, Err: err, Duration: duration}) })
+}
+
+func (p *permit) OnResult(result any, duration time.Duration) {
+	p.once.Do(func() { p.breaker.record(Call{Kind: CallResult, Result: result, Duration: duration}) })
+}
+
+func (p *permit) Release() {
+	p.once.Do(func() { p.breaker.record(Call{Kind: CallReleased}) })
+}
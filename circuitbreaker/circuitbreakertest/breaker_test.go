@@ -0,0 +1,71 @@
+package circuitbreakertest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/backoff"
+	"github.com/hugolhafner/dskit/circuitbreaker"
+	"github.com/hugolhafner/dskit/circuitbreaker/circuitbreakertest"
+	"github.com/hugolhafner/dskit/retry"
+)
+
+var errDependency = errors.New("dependency failed")
+
+func TestBreaker_RecordsCalls(t *testing.T) {
+	cb := circuitbreakertest.New("fake")
+
+	v, err := circuitbreaker.Execute(context.Background(), cb, func(context.Context) (int, error) { return 1, nil })
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+
+	require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, func(context.Context) error { return errDependency }), errDependency)
+
+	calls := cb.Calls()
+	require.Len(t, calls, 2)
+	require.Equal(t, circuitbreakertest.CallResult, calls[0].Kind)
+	require.Equal(t, 1, calls[0].Result)
+	require.Equal(t, circuitbreakertest.CallError, calls[1].Kind)
+	require.ErrorIs(t, calls[1].Err, errDependency)
+
+	cb.SetState(circuitbreaker.StateOpen)
+	require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, func(context.Context) error { return nil }), circuitbreaker.ErrOpenState)
+	require.Equal(t, int64(1), cb.Rejections())
+	require.Len(t, cb.Calls(), 2)
+}
+
+func TestBreaker_ScriptedPermissions(t *testing.T) {
+	cb := circuitbreakertest.New("fake")
+	cb.Script(circuitbreaker.ErrHalfOpenState, nil)
+	cb.SetState(circuitbreaker.StateOpen)
+
+	policy := retry.MustNewPolicy(
+		"fake", retry.WithMaxAttempts(5), retry.WithBackoff(backoff.NewFixed(time.Millisecond)),
+	)
+
+	attempts := 0
+	err := retry.DoWithCircuit(
+		context.Background(), policy, cb, func(context.Context) error {
+			attempts++
+			return nil
+		},
+	)
+
+	// the scripted rejection is retried and the scripted permit is used despite the open state
+	require.NoError(t, err)
+	require.Equal(t, 1, attempts)
+	require.Equal(t, int64(1), cb.Rejections())
+	require.Len(t, cb.Calls(), 1)
+
+	// the circuit aware policy stops retrying once the circuit breaker rejects a call
+	err = retry.DoWithCircuit(
+		context.Background(), retry.MustNewCircuitAwarePolicy("fake", retry.WithMaxAttempts(5)), cb,
+		func(context.Context) error { return nil },
+	)
+	require.ErrorIs(t, err, circuitbreaker.ErrOpenState)
+	require.Equal(t, int64(2), cb.Rejections())
+}
@@ -0,0 +1,177 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hugolhafner/dskit/timeout"
+)
+
+// ErrorWeight makes failures matching Err count Weight times towards the rates
+type ErrorWeight struct {
+	Err    error
+	Weight float64
+}
+
+// Classifier decides the outcome of a call from its result, error and duration. Config.Classifier
+// returns the one of a circuit breaker, so other strategies can reuse the same failure semantics.
+type Classifier struct {
+	// SlowCallDurationThreshold is the duration above which a call is considered slow, zero disables
+	// slow call detection
+	SlowCallDurationThreshold time.Duration
+
+	FailOnResultPredicate func(result any) bool
+	FailOnErrorPredicate  func(error) bool
+
+	FailErrors   []error
+	IgnoreErrors []error
+
+	// ErrorWeights are matched in order with errors.Is, failures matching none weigh 1
+	ErrorWeights []ErrorWeight
+
+	// CategorizeError overrides the category of a failed call, returning an empty
+	// category falls back to FailureCategoryTimeout or FailureCategoryError
+	CategorizeError func(error) FailureCategory
+}
+
+// Classify returns the outcome of a call
+func (c *Classifier) Classify(result any, err error, duration time.Duration) CallOutcome {
+	return c.classify(result, err, duration, c.SlowCallDurationThreshold)
+}
+
+func (c *Classifier) classify(result any, err error, duration, slowCallDurationThreshold time.Duration) CallOutcome {
+	if c.IsIgnored(err) {
+		return OutcomeIgnored
+	}
+
+	isFailure := c.IsFailure(result, err)
+	isSlow := slowCallDurationThreshold > 0 && duration >= slowCallDurationThreshold
+
+	switch {
+	case isFailure && isTimeout(err):
+		// the duration of a timeout is its deadline, so it is not classified as slow
+		return OutcomeTimeout
+	case isFailure && isSlow:
+		return OutcomeSlowFailure
+	case isFailure:
+		return OutcomeFailure
+	case isSlow:
+		return OutcomeSlowSuccess
+	default:
+		return OutcomeSuccess
+	}
+}
+
+// ClassifyCall returns the record of a call, with the category and weight of a failure
+func (c *Classifier) ClassifyCall(result any, err error, duration time.Duration) CallRecord {
+	return c.classifyCall(result, err, duration, c.SlowCallDurationThreshold)
+}
+
+func (c *Classifier) classifyCall(result any, err error, duration, slowCallDurationThreshold time.Duration) CallRecord {
+	record := CallRecord{
+		Outcome: c.classify(result, err, duration, slowCallDurationThreshold),
+		Weight:  1,
+	}
+
+	if record.Outcome.IsFailure() {
+		record.Category = c.Categorize(err)
+		record.Weight = c.Weight(err)
+	}
+
+	return record
+}
+
+// IsFailure reports whether a call should be counted as a failure. The error predicate and
+// FailErrors take precedence over IgnoreErrors, any other error is a failure. An error matching
+// timeout.ErrTimeout is a failure even when the cause it wraps is ignored, unless IgnoreErrors
+// contains timeout.ErrTimeout itself.
+func (c *Classifier) IsFailure(result any, err error) bool {
+	if err != nil {
+		return !c.IsIgnored(err)
+	}
+
+	if c.FailOnResultPredicate != nil {
+		return c.FailOnResultPredicate(result)
+	}
+
+	return false
+}
+
+// IsIgnored reports whether err matches IgnoreErrors and none of the rules that make it a failure
+func (c *Classifier) IsIgnored(err error) bool {
+	if err == nil || c.failsOnError(err) {
+		return false
+	}
+
+	timedOut := errors.Is(err, timeout.ErrTimeout)
+	for _, ignoreErr := range c.IgnoreErrors {
+		if !errors.Is(err, ignoreErr) {
+			continue
+		}
+
+		// the dependency did not answer in time, whatever the error it was cancelled with
+		if timedOut && !errors.Is(ignoreErr, timeout.ErrTimeout) {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (c *Classifier) failsOnError(err error) bool {
+	if c.FailOnErrorPredicate != nil && c.FailOnErrorPredicate(err) {
+		return true
+	}
+
+	for _, failErr := range c.FailErrors {
+		if errors.Is(err, failErr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Categorize returns the category of a failure caused by err, a failure without an
+// error, e.g. from FailOnResultPredicate, is a FailureCategoryError
+func (c *Classifier) Categorize(err error) FailureCategory {
+	if err != nil && c.CategorizeError != nil {
+		if category := c.CategorizeError(err); category != "" {
+			return category
+		}
+	}
+
+	if isTimeout(err) {
+		return FailureCategoryTimeout
+	}
+
+	return FailureCategoryError
+}
+
+func isTimeout(err error) bool {
+	return errors.Is(err, timeout.ErrTimeout) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// isCallerCancellation reports whether a call failed after ctx, the context of its caller, was
+// cancelled. Its error is then most likely caused by the cancellation rather than the dependency.
+func isCallerCancellation(ctx context.Context, err error) bool {
+	return ctx != nil && err != nil && errors.Is(ctx.Err(), context.Canceled)
+}
+
+// Weight returns how much a failure caused by err counts towards the rates
+func (c *Classifier) Weight(err error) float64 {
+	if err == nil {
+		return 1
+	}
+
+	for _, w := range c.ErrorWeights {
+		if errors.Is(err, w.Err) {
+			return w.Weight
+		}
+	}
+
+	return 1
+}
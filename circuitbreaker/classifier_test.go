@@ -0,0 +1,242 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+	"github.com/hugolhafner/dskit/timeout"
+)
+
+var (
+	errIgnored    = errors.New("ignored")
+	errOverloaded = errors.New("overloaded")
+)
+
+func TestClassifier_ClassifyCall(t *testing.T) {
+	c := circuitbreaker.Classifier{
+		IgnoreErrors: []error{errIgnored},
+		ErrorWeights: []circuitbreaker.ErrorWeight{{Err: errOverloaded, Weight: 3}},
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want circuitbreaker.CallRecord
+	}{
+		{
+			name: "success",
+			want: circuitbreaker.CallRecord{Outcome: circuitbreaker.OutcomeSuccess, Weight: 1},
+		},
+		{
+			name: "ignored",
+			err:  fmt.Errorf("wrapped: %w", errIgnored),
+			want: circuitbreaker.CallRecord{Outcome: circuitbreaker.OutcomeIgnored, Weight: 1},
+		},
+		{
+			name: "timeout of an ignored error",
+			err:  fmt.Errorf("%w: %w", timeout.ErrTimeout, errIgnored),
+			want: circuitbreaker.CallRecord{
+				Outcome:  circuitbreaker.OutcomeTimeout,
+				Category: circuitbreaker.FailureCategoryTimeout,
+				Weight:   1,
+			},
+		},
+		{
+			name: "deadline exceeded",
+			err:  context.DeadlineExceeded,
+			want: circuitbreaker.CallRecord{
+				Outcome:  circuitbreaker.OutcomeTimeout,
+				Category: circuitbreaker.FailureCategoryTimeout,
+				Weight:   1,
+			},
+		},
+		{
+			name: "weighted error",
+			err:  errOverloaded,
+			want: circuitbreaker.CallRecord{
+				Outcome:  circuitbreaker.OutcomeFailure,
+				Category: circuitbreaker.FailureCategoryError,
+				Weight:   3,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				require.Equal(t, tt.want, c.ClassifyCall(nil, tt.err, time.Millisecond))
+			},
+		)
+	}
+}
+
+func TestClassifier_IgnoredTimeouts(t *testing.T) {
+	timedOut := fmt.Errorf("%w: %w", timeout.ErrTimeout, errIgnored)
+
+	tests := []struct {
+		name   string
+		ignore []error
+		err    error
+		want   circuitbreaker.CallOutcome
+	}{
+		{name: "ignored cause", ignore: []error{errIgnored}, err: timedOut, want: circuitbreaker.OutcomeTimeout},
+		{name: "ignored timeout", ignore: []error{timeout.ErrTimeout}, err: timedOut, want: circuitbreaker.OutcomeIgnored},
+		{
+			name:   "ignored timeout and cause",
+			ignore: []error{errIgnored, timeout.ErrTimeout},
+			err:    timedOut,
+			want:   circuitbreaker.OutcomeIgnored,
+		},
+		{
+			name:   "bare ignored timeout",
+			ignore: []error{timeout.ErrTimeout},
+			err:    timeout.ErrTimeout,
+			want:   circuitbreaker.OutcomeIgnored,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				c := circuitbreaker.Classifier{IgnoreErrors: tt.ignore}
+				require.Equal(t, tt.want, c.Classify(nil, tt.err, time.Millisecond))
+			},
+		)
+	}
+}
+
+func TestConfig_Classifier(t *testing.T) {
+	config := circuitbreaker.Config{
+		SlowCallDurationThreshold: time.Second,
+		FailErrors:                []error{errOverloaded},
+		IgnoreErrors:              []error{errIgnored, errOverloaded},
+	}
+	c := config.Classifier()
+
+	require.Equal(t, circuitbreaker.OutcomeSlowSuccess, c.Classify(nil, nil, time.Second))
+	require.Equal(t, circuitbreaker.OutcomeIgnored, c.Classify(nil, errIgnored, time.Millisecond))
+	require.Equal(t, circuitbreaker.OutcomeFailure, c.Classify(nil, errOverloaded, time.Millisecond))
+
+	// a zero threshold disables slow call detection
+	require.Equal(t, circuitbreaker.OutcomeSuccess, (&circuitbreaker.Classifier{}).Classify(nil, nil, time.Hour))
+}
+
+func TestCountWindow_WeightedCategoryRates(t *testing.T) {
+	w := circuitbreaker.NewCountWindow(10)
+
+	w.Record(circuitbreaker.CallRecord{Outcome: circuitbreaker.OutcomeSuccess, Weight: 1})
+	w.Record(circuitbreaker.CallRecord{Outcome: circuitbreaker.OutcomeIgnored, Weight: 1})
+	w.Record(
+		circuitbreaker.CallRecord{
+			Outcome: circuitbreaker.OutcomeFailure, Category: circuitbreaker.FailureCategoryError, Weight: 1,
+		},
+	)
+	w.Record(
+		circuitbreaker.CallRecord{
+			Outcome: circuitbreaker.OutcomeSlowFailure, Category: circuitbreaker.FailureCategoryTimeout, Weight: 2,
+		},
+	)
+
+	rates := w.CallRates()
+	require.Equal(t, 3, rates.TotalCalls)
+	require.InDelta(t, 0.25, rates.SuccessRate, 1e-9)
+	require.InDelta(t, 0.75, rates.FailureRate, 1e-9)
+	require.InDelta(t, 0.5, rates.SlowCallRate, 1e-9)
+	require.InDelta(t, 25, rates.SuccessRatePercent, 1e-9)
+	require.InDelta(t, 75, rates.FailureRatePercent, 1e-9)
+	require.InDelta(t, 50, rates.SlowCallRatePercent, 1e-9)
+	require.InDelta(t, 25, rates.CategoryRatesPercent[circuitbreaker.FailureCategoryError], 1e-9)
+	require.InDelta(t, 50, rates.CategoryRatesPercent[circuitbreaker.FailureCategoryTimeout], 1e-9)
+}
+
+func TestCircuitBreaker_CategoryRateThreshold(t *testing.T) {
+	cb := circuitbreaker.New(
+		"timeouts",
+		circuitbreaker.WithMetrics(&circuitbreaker.NoopMetrics{}),
+		circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)),
+		circuitbreaker.WithMinimumNumberOfCalls(4),
+		circuitbreaker.WithCategoryRateThreshold(circuitbreaker.FailureCategoryTimeout, 20),
+	)
+
+	timingOut := func(context.Context) error { return timeout.ErrTimeout }
+
+	// one error in four stays below the overall failure rate threshold, one timeout in five does not
+	for range 3 {
+		require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+	}
+	require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, failingCall), errDependency)
+	require.Equal(t, circuitbreaker.StateClosed, cb.State())
+
+	require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, timingOut), timeout.ErrTimeout)
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+}
+
+func TestCircuitBreaker_IgnoredCallsAreNotRecorded(t *testing.T) {
+	memory := circuitbreaker.NewInMemoryMetrics()
+	cb := halfOpenBreaker(
+		t, &fakeClock{now: time.Unix(1000, 0)},
+		circuitbreaker.WithMetrics(memory),
+		circuitbreaker.WithIgnoreErrors(errIgnored),
+	)
+
+	ignoredCall := func(context.Context) error { return errIgnored }
+
+	// ignored calls hand their half-open permit back, so they never decide the state
+	for range 5 {
+		require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, ignoredCall), errIgnored)
+	}
+	require.Equal(t, circuitbreaker.StateHalfOpen, cb.State())
+	require.Zero(t, cb.Metrics().BufferedCalls)
+	require.Equal(t, 3, cb.Metrics().HalfOpenPermitsAvailable)
+	stats, ok := memory.GetMetrics("half-open")
+	require.True(t, ok)
+	require.Equal(t, int64(5), stats.Calls[circuitbreaker.OutcomeIgnored])
+
+	for range 3 {
+		require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+	}
+	require.Equal(t, circuitbreaker.StateClosed, cb.State())
+}
+
+func TestCircuitBreaker_CallerCancellationIsNotRecorded(t *testing.T) {
+	memory := circuitbreaker.NewInMemoryMetrics()
+	cb := circuitbreaker.New(
+		"cancellations",
+		circuitbreaker.WithMetrics(memory),
+		circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)),
+		circuitbreaker.WithMinimumNumberOfCalls(2),
+	)
+
+	cancelledCall := func(ctx context.Context) error {
+		<-ctx.Done()
+		return fmt.Errorf("request aborted: %w", ctx.Err())
+	}
+
+	for range 3 {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(time.Millisecond, cancel)
+
+		require.ErrorIs(t, circuitbreaker.Do(ctx, cb, cancelledCall), context.Canceled)
+	}
+	require.Equal(t, circuitbreaker.StateClosed, cb.State())
+	require.Zero(t, cb.Metrics().BufferedCalls)
+
+	// a deadline is a timeout of the dependency rather than a decision of the caller
+	for range 2 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		require.ErrorIs(t, circuitbreaker.Do(ctx, cb, cancelledCall), context.DeadlineExceeded)
+		cancel()
+	}
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+
+	stats, ok := memory.GetMetrics("cancellations")
+	require.True(t, ok)
+	require.Equal(t, int64(3), stats.Calls[circuitbreaker.OutcomeCancelled])
+	require.Equal(t, int64(2), stats.Calls[circuitbreaker.OutcomeTimeout])
+}
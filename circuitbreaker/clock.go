@@ -0,0 +1,36 @@
+package circuitbreaker
+
+import (
+	"time"
+)
+
+// Clock is the source of time for a circuit breaker, it can be replaced for deterministic tests
+type Clock interface {
+	Now() time.Time
+
+	// AfterFunc calls f in its own goroutine once d has elapsed
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending call scheduled with Clock.AfterFunc
+type Timer interface {
+	// Stop prevents the call if it has not run yet, it reports whether it did
+	Stop() bool
+}
+
+var _ Clock = (*systemClock)(nil)
+
+type systemClock struct{}
+
+// SystemClock returns the Clock backed by the time package
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
@@ -0,0 +1,162 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+var _ circuitbreaker.Clock = (*fakeClock)(nil)
+
+// fakeClock only moves when advanced, due timers run in the goroutine calling Advance
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	at    time.Time
+	f     func()
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) circuitbreaker.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer := &fakeTimer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, timer)
+	return timer
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+
+	var due []*fakeTimer
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+		} else {
+			due = append(due, timer)
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+
+	for _, timer := range due {
+		timer.f()
+	}
+}
+
+func automaticBreaker(clock *fakeClock, opts ...circuitbreaker.Option) circuitbreaker.CircuitBreaker {
+	opts = append(
+		[]circuitbreaker.Option{
+			circuitbreaker.WithClock(clock),
+			circuitbreaker.WithAutomaticTransitionFromOpenToHalfOpen(),
+			circuitbreaker.WithTripStrategy(circuitbreaker.NewConsecutiveTripStrategy(1, 1)),
+			circuitbreaker.WithWaitDurationInOpenState(time.Minute),
+		}, opts...,
+	)
+
+	return circuitbreaker.New("automatic", opts...)
+}
+
+func TestCircuitBreaker_AutomaticTransitionToHalfOpen(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	metrics := circuitbreaker.NewInMemoryMetrics()
+	cb := automaticBreaker(clock, circuitbreaker.WithMetrics(metrics))
+	t.Cleanup(func() { require.NoError(t, cb.Close()) })
+
+	_ = circuitbreaker.Do(context.Background(), cb, failingCall)
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+
+	clock.Advance(59 * time.Second)
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+
+	// no call is needed for the transition to be reported
+	clock.Advance(time.Second)
+	require.Equal(t, circuitbreaker.StateHalfOpen, cb.State())
+
+	stats, _ := metrics.GetMetrics("automatic")
+	require.Equal(t, circuitbreaker.StateHalfOpen, stats.State)
+}
+
+func TestCircuitBreaker_AutomaticTransitionFollowsRestoredTransitionTime(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	cb := automaticBreaker(clock)
+	t.Cleanup(func() { require.NoError(t, cb.Close()) })
+
+	require.NoError(
+		t, cb.Restore(
+			circuitbreaker.Snapshot{
+				Name:           "automatic",
+				State:          circuitbreaker.StateOpen,
+				TransitionTime: clock.Now().Add(-30 * time.Second),
+			},
+		),
+	)
+
+	clock.Advance(30 * time.Second)
+	require.Equal(t, circuitbreaker.StateHalfOpen, cb.State())
+}
+
+func TestCircuitBreaker_AutomaticTransitionCancelled(t *testing.T) {
+	t.Run(
+		"manual transition", func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(1000, 0)}
+			cb := automaticBreaker(clock)
+			t.Cleanup(func() { require.NoError(t, cb.Close()) })
+
+			_ = circuitbreaker.Do(context.Background(), cb, failingCall)
+			require.NoError(t, cb.Restore(circuitbreaker.Snapshot{Name: "automatic", State: circuitbreaker.StateClosed}))
+
+			clock.Advance(time.Minute)
+			require.Equal(t, circuitbreaker.StateClosed, cb.State())
+		},
+	)
+
+	t.Run(
+		"close", func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(1000, 0)}
+			cb := automaticBreaker(clock)
+
+			_ = circuitbreaker.Do(context.Background(), cb, failingCall)
+			require.NoError(t, cb.Close())
+
+			clock.Advance(time.Minute)
+			require.Equal(t, circuitbreaker.StateOpen, cb.State())
+
+			// a closed circuit breaker still transitions when a call arrives
+			require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+			require.Equal(t, circuitbreaker.StateClosed, cb.State())
+		},
+	)
+}
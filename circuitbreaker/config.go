@@ -2,6 +2,8 @@ package circuitbreaker
 
 import (
 	"time"
+
+	"github.com/hugolhafner/dskit/backoff"
 )
 
 type Config struct {
@@ -9,10 +11,17 @@ type Config struct {
 
 	Metrics Metrics
 
+	// Clock is the source of time for state transitions and of windows that read the time
+	Clock Clock
+
 	// MetricsOnlyMode starts the circuit breaker in metrics only mode,
 	// where it does not block any calls but still collects metrics
 	MetricsOnlyMode bool
 
+	// TripStrategy decides when the circuit breaker opens and closes, nil uses a RateTripStrategy
+	// built from MinimumNumberOfCalls, FailureRateThreshold and SlowCallRateThreshold
+	TripStrategy TripStrategy
+
 	// MinimumNumberOfCalls is the minimum number of calls required before
 	// the circuit breaker evaluates the failure rate and slow call rate
 	MinimumNumberOfCalls int
@@ -23,9 +32,18 @@ type Config struct {
 	// SlowCallRateThreshold is the slow call rate threshold in percentage to trip the circuit breaker
 	SlowCallRateThreshold float64
 
-	// SlowCallDurationThreshold is the duration above which a call is considered slow
+	// SlowCallDurationThreshold is the duration above which a call is considered slow, zero disables
+	// slow call detection
 	SlowCallDurationThreshold time.Duration
 
+	// CategoryRateThresholds are failure rate thresholds in percentage of single categories,
+	// checked next to FailureRateThreshold by the default TripStrategy
+	CategoryRateThresholds map[FailureCategory]float64
+
+	// AdaptiveSlowCallThreshold replaces SlowCallDurationThreshold with a threshold derived from the
+	// recent latency once the first tenth of its window elapsed, nil keeps the static threshold
+	AdaptiveSlowCallThreshold *AdaptiveSlowCallThreshold
+
 	// PermittedNumberOfCallsInHalfOpenState is the number of permitted calls when the circuit breaker is half-open
 	// before evaluating the thresholds again
 	PermittedNumberOfCallsInHalfOpenState int
@@ -33,11 +51,68 @@ type Config struct {
 	// WaitDurationInOpenState is the duration the circuit breaker stays open before transitioning to half-open
 	WaitDurationInOpenState time.Duration
 
+	// MaxWaitDurationInHalfOpenState bounds the time in the half-open state when not every permitted
+	// call completes. Once it elapsed the circuit breaker is decided on the calls completed so far,
+	// or opens again if none completed. Zero waits for every permitted call.
+	MaxWaitDurationInHalfOpenState time.Duration
+
+	// AutomaticTransitionFromOpenToHalfOpen schedules the transition to half-open when the wait in the
+	// open state elapsed, instead of waiting for the next call. Without it an idle circuit breaker
+	// reports the open state until a call arrives.
+	AutomaticTransitionFromOpenToHalfOpen bool
+
+	// WaitDurationInOpenStateBackoff replaces the fixed WaitDurationInOpenState when set. The n-th
+	// consecutive opening waits Next(n), so the wait grows every time the half-open state fails
+	// and resets once the circuit breaker closes.
+	WaitDurationInOpenStateBackoff backoff.Backoff
+
+	// StateStore shares the state and window counts with other instances of the circuit breaker,
+	// nil keeps them local to this instance
+	StateStore StateStore
+
+	// InstanceID identifies this instance in the StateStore, defaults to the hostname and process id
+	InstanceID string
+
+	// StateSyncInterval is the longest the circuit breaker goes without syncing with the StateStore
+	// while it is receiving calls, which bounds how stale its view of the shared state is.
+	// Local transitions are published right away regardless of the interval. Syncs run in the
+	// background, calls never wait for the store and see the shared state once a sync completed.
+	StateSyncInterval time.Duration
+
+	// StateSyncTimeout bounds every StateStore operation, on errors the circuit breaker keeps
+	// working on its local state and retries on the next sync
+	StateSyncTimeout time.Duration
+
+	// SharedCountsMaxAge is how long counts reported by an instance are included in the
+	// aggregated counts, so instances that stopped reporting age out
+	SharedCountsMaxAge time.Duration
+
 	FailOnResultPredicate func(result any) bool
 	FailOnErrorPredicate  func(error) bool
 
 	FailErrors   []error
 	IgnoreErrors []error
+
+	// ErrorWeights are matched in order with errors.Is, failures matching none weigh 1
+	ErrorWeights []ErrorWeight
+
+	// CategorizeError overrides the category of a failed call, returning an empty
+	// category falls back to FailureCategoryTimeout or FailureCategoryError
+	CategorizeError func(error) FailureCategory
+}
+
+// Classifier returns a Classifier with the classification fields of the config, e.g. to classify
+// the calls of another strategy the same way as the circuit breaker
+func (c *Config) Classifier() Classifier {
+	return Classifier{
+		SlowCallDurationThreshold: c.SlowCallDurationThreshold,
+		FailOnResultPredicate:     c.FailOnResultPredicate,
+		FailOnErrorPredicate:      c.FailOnErrorPredicate,
+		FailErrors:                c.FailErrors,
+		IgnoreErrors:              c.IgnoreErrors,
+		ErrorWeights:              c.ErrorWeights,
+		CategorizeError:           c.CategorizeError,
+	}
 }
 
 type Option func(*Config)
@@ -45,6 +120,7 @@ type Option func(*Config)
 func defaultConfig() Config {
 	return Config{
 		Window:                                NewCountWindow(100),
+		Clock:                                 SystemClock(),
 		MetricsOnlyMode:                       false,
 		MinimumNumberOfCalls:                  20,
 		FailureRateThreshold:                  50.0,
@@ -52,6 +128,9 @@ func defaultConfig() Config {
 		SlowCallDurationThreshold:             10 * time.Second,
 		PermittedNumberOfCallsInHalfOpenState: 10,
 		WaitDurationInOpenState:               60 * time.Second,
+		StateSyncInterval:                     time.Second,
+		StateSyncTimeout:                      time.Second,
+		SharedCountsMaxAge:                    10 * time.Second,
 	}
 }
 
@@ -73,6 +152,12 @@ func WithWindow(window Window) Option {
 	}
 }
 
+func WithTripStrategy(strategy TripStrategy) Option {
+	return func(c *Config) {
+		c.TripStrategy = strategy
+	}
+}
+
 func WithMinimumNumberOfCalls(n int) Option {
 	return func(c *Config) {
 		c.MinimumNumberOfCalls = n
@@ -91,12 +176,34 @@ func WithSlowCallRateThreshold(threshold float64) Option {
 	}
 }
 
+// WithCategoryRateThreshold trips the circuit breaker once the failure rate of category reaches threshold
+func WithCategoryRateThreshold(category FailureCategory, threshold float64) Option {
+	return func(c *Config) {
+		if c.CategoryRateThresholds == nil {
+			c.CategoryRateThresholds = make(map[FailureCategory]float64)
+		}
+		c.CategoryRateThresholds[category] = threshold
+	}
+}
+
 func WithSlowCallDurationThreshold(duration time.Duration) Option {
 	return func(c *Config) {
 		c.SlowCallDurationThreshold = duration
 	}
 }
 
+// WithAdaptiveSlowCallThreshold classifies calls as slow when they take longer than multiplier
+// times the percentile of the call durations over window, e.g. 3 times the p50 over a minute
+func WithAdaptiveSlowCallThreshold(percentile, multiplier float64, window time.Duration) Option {
+	return func(c *Config) {
+		c.AdaptiveSlowCallThreshold = &AdaptiveSlowCallThreshold{
+			Percentile: percentile,
+			Multiplier: multiplier,
+			Window:     window,
+		}
+	}
+}
+
 func WithPermittedNumberOfCallsInHalfOpenState(n int) Option {
 	return func(c *Config) {
 		c.PermittedNumberOfCallsInHalfOpenState = n
@@ -109,6 +216,59 @@ func WithWaitDurationInOpenState(duration time.Duration) Option {
 	}
 }
 
+// WithStateStore shares the state of the circuit breaker with every instance using the same store
+// and name, an empty instanceID defaults to the hostname and process id
+func WithStateStore(store StateStore, instanceID string) Option {
+	return func(c *Config) {
+		c.StateStore = store
+		c.InstanceID = instanceID
+	}
+}
+
+func WithStateSyncInterval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.StateSyncInterval = interval
+	}
+}
+
+func WithStateSyncTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.StateSyncTimeout = timeout
+	}
+}
+
+func WithSharedCountsMaxAge(maxAge time.Duration) Option {
+	return func(c *Config) {
+		c.SharedCountsMaxAge = maxAge
+	}
+}
+
+func WithMaxWaitDurationInHalfOpenState(duration time.Duration) Option {
+	return func(c *Config) {
+		c.MaxWaitDurationInHalfOpenState = duration
+	}
+}
+
+func WithClock(clock Clock) Option {
+	return func(c *Config) {
+		c.Clock = clock
+	}
+}
+
+// WithAutomaticTransitionFromOpenToHalfOpen transitions to half-open with a timer once the wait in
+// the open state elapsed, Close the circuit breaker to stop the timer when it is no longer used
+func WithAutomaticTransitionFromOpenToHalfOpen() Option {
+	return func(c *Config) {
+		c.AutomaticTransitionFromOpenToHalfOpen = true
+	}
+}
+
+func WithWaitDurationInOpenStateBackoff(b backoff.Backoff) Option {
+	return func(c *Config) {
+		c.WaitDurationInOpenStateBackoff = b
+	}
+}
+
 func WithFailOnResultPredicate(predicate func(result any) bool) Option {
 	return func(c *Config) {
 		c.FailOnResultPredicate = predicate
@@ -120,6 +280,7 @@ func WithFailOnErrorPredicate(predicate func(error) bool) Option {
 		c.FailOnErrorPredicate = predicate
 	}
 }
+
 func WithFailErrors(errors ...error) Option {
 	return func(c *Config) {
 		c.FailErrors = errors
@@ -131,3 +292,15 @@ func WithIgnoreErrors(errors ...error) Option {
 		c.IgnoreErrors = errors
 	}
 }
+
+func WithErrorWeights(weights ...ErrorWeight) Option {
+	return func(c *Config) {
+		c.ErrorWeights = weights
+	}
+}
+
+func WithCategorizeError(categorize func(error) FailureCategory) Option {
+	return func(c *Config) {
+		c.CategorizeError = categorize
+	}
+}
@@ -45,21 +45,56 @@ func safeExecute[T any](ctx context.Context, fn func(ctx context.Context) (T, er
 	return fn(ctx)
 }
 
-func Execute[T any](ctx context.Context, cb CircuitBreaker, fn func(context.Context) (T, error)) (T, error) {
+func tryAcquirePermission(ctx context.Context, g Guard) (Permit, error) {
+	if cg, ok := g.(ContextGuard); ok {
+		return cg.TryAcquirePermissionContext(ctx)
+	}
+
+	return g.TryAcquirePermission()
+}
+
+// Execute runs fn if the guard permits it and records its outcome. When ctx is cancelled before
+// fn returns, a permit of the half-open state is handed to another call and fn's outcome is only
+// reported to the metrics. A call that fails after ctx was cancelled is recorded as
+// OutcomeCancelled, one that fails after the deadline of ctx expired counts like any other
+// timeout, so it still decides the half-open state. A threshold set with
+// ContextWithSlowCallDurationThreshold on ctx decides whether the call is slow.
+func Execute[T any](ctx context.Context, g Guard, fn func(context.Context) (T, error)) (T, error) {
 	var zero T
-	if err := cb.before(); err != nil {
+	permit, err := tryAcquirePermission(ctx, g)
+	if err != nil {
 		return zero, err
 	}
 
+	stop := context.AfterFunc(
+		ctx, func() {
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				return
+			}
+
+			if reclaimer, ok := permit.(leaseReclaimer); ok {
+				reclaimer.reclaim()
+			} else {
+				permit.Release()
+			}
+		},
+	)
 	start := time.Now()
 
 	result, err := safeExecute(ctx, fn)
-	cb.after(result, err, time.Since(start))
+
+	stop()
+	if err != nil {
+		permit.OnError(time.Since(start), err)
+	} else {
+		permit.OnResult(result, time.Since(start))
+	}
+
 	return result, err
 }
 
-func Do(ctx context.Context, cb CircuitBreaker, fn func(context.Context) error) (err error) {
-	_, err = Execute(ctx, cb, func(ctx context.Context) (any, error) {
+func Do(ctx context.Context, g Guard, fn func(context.Context) error) (err error) {
+	_, err = Execute(ctx, g, func(ctx context.Context) (any, error) {
 		return nil, fn(ctx)
 	})
 
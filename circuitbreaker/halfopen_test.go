@@ -0,0 +1,158 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+func halfOpenBreaker(t *testing.T, clock *fakeClock, opts ...circuitbreaker.Option) circuitbreaker.CircuitBreaker {
+	opts = append(
+		[]circuitbreaker.Option{
+			circuitbreaker.WithClock(clock),
+			circuitbreaker.WithPermittedNumberOfCallsInHalfOpenState(3),
+			circuitbreaker.WithMaxWaitDurationInHalfOpenState(10 * time.Second),
+		}, opts...,
+	)
+
+	cb := circuitbreaker.New("half-open", opts...)
+	t.Cleanup(func() { require.NoError(t, cb.Close()) })
+
+	require.NoError(
+		t, cb.Restore(
+			circuitbreaker.Snapshot{
+				Name:           "half-open",
+				State:          circuitbreaker.StateHalfOpen,
+				TransitionTime: clock.Now(),
+			},
+		),
+	)
+
+	return cb
+}
+
+func TestCircuitBreaker_MaxWaitDurationInHalfOpenState(t *testing.T) {
+	t.Run(
+		"decides on completed calls", func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(1000, 0)}
+			cb := halfOpenBreaker(t, clock)
+
+			// only one of three permitted calls ever completes
+			require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+			clock.Advance(9 * time.Second)
+			require.Equal(t, circuitbreaker.StateHalfOpen, cb.State())
+
+			clock.Advance(time.Second)
+			require.Equal(t, circuitbreaker.StateClosed, cb.State())
+		},
+	)
+
+	t.Run(
+		"opens without completed calls", func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(1000, 0)}
+			cb := halfOpenBreaker(t, clock)
+
+			clock.Advance(10 * time.Second)
+			require.Equal(t, circuitbreaker.StateOpen, cb.State())
+		},
+	)
+
+	t.Run(
+		"checked on calls without the timer", func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(1000, 0)}
+			cb := halfOpenBreaker(t, clock)
+			require.NoError(t, cb.Close())
+
+			require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, failingCall), errDependency)
+			clock.Advance(10 * time.Second)
+			require.Equal(t, circuitbreaker.StateHalfOpen, cb.State())
+
+			require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, succeedingCall), circuitbreaker.ErrOpenState)
+		},
+	)
+
+	t.Run(
+		"waits in the open state after expiring", func(t *testing.T) {
+			clock := &fakeClock{now: time.Unix(1000, 0)}
+			cb := halfOpenBreaker(t, clock, circuitbreaker.WithWaitDurationInOpenState(5*time.Second))
+			require.NoError(t, cb.Close())
+
+			// the half-open state waited longer than the open wait, which starts over when it expires
+			clock.Advance(10 * time.Second)
+			require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, succeedingCall), circuitbreaker.ErrOpenState)
+			require.Equal(t, circuitbreaker.StateOpen, cb.State())
+
+			clock.Advance(5 * time.Second)
+			require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+		},
+	)
+}
+
+func TestCircuitBreaker_ReclaimsLeasesOfCancelledCalls(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	cb := halfOpenBreaker(t, clock, circuitbreaker.WithPermittedNumberOfCallsInHalfOpenState(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+
+	go func() {
+		done <- circuitbreaker.Do(
+			ctx, cb, func(context.Context) error {
+				close(started)
+				<-release
+				return errDependency
+			},
+		)
+	}()
+
+	<-started
+	require.Zero(t, cb.Metrics().HalfOpenPermitsAvailable)
+	require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, succeedingCall), circuitbreaker.ErrHalfOpenState)
+
+	// the caller gives up while the call is still running
+	cancel()
+	require.Eventually(
+		t, func() bool { return cb.Metrics().HalfOpenPermitsAvailable == 1 }, time.Second, time.Millisecond,
+	)
+
+	require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+	require.Equal(t, circuitbreaker.StateClosed, cb.State())
+
+	// the abandoned call completing late is not counted
+	close(release)
+	require.ErrorIs(t, <-done, errDependency)
+	require.Equal(t, circuitbreaker.StateClosed, cb.State())
+	require.Zero(t, cb.Metrics().BufferedCalls)
+}
+
+func TestCircuitBreaker_RecordsHalfOpenCallsPastTheirDeadline(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	memory := circuitbreaker.NewInMemoryMetrics()
+	cb := halfOpenBreaker(
+		t, clock, circuitbreaker.WithPermittedNumberOfCallsInHalfOpenState(1), circuitbreaker.WithMetrics(memory),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	err := circuitbreaker.Do(
+		ctx, cb, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the permit is not handed to another call, the timeout decides the half-open state
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+
+	stats, ok := memory.GetMetrics("half-open")
+	require.True(t, ok)
+	require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeTimeout])
+}
@@ -16,6 +16,9 @@ type StateTransition struct {
 	FromState State
 	ToState   State
 	Timestamp time.Time
+
+	// WaitDurationInOpenState is how long the circuit breaker stays open, only set when ToState is StateOpen
+	WaitDurationInOpenState time.Duration
 }
 
 // CallResult represents the result of a call through the circuit breaker
@@ -33,13 +36,61 @@ type CallRejection struct {
 	Error error
 }
 
-// CallRates represents the current call rate statistics
+// CallRates represents the current call rate statistics. The rates are ratios from 0 to 1, the
+// Percent fields hold the same rates in percentage, the unit of the rate thresholds.
 type CallRates struct {
 	Name         string
 	SuccessRate  float64
 	FailureRate  float64
 	SlowCallRate float64
 	TotalCalls   int
+
+	SuccessRatePercent  float64
+	FailureRatePercent  float64
+	SlowCallRatePercent float64
+
+	// CategoryRatesPercent is the failure rate of each category in percentage, together they add
+	// up to FailureRatePercent
+	CategoryRatesPercent map[FailureCategory]float64
+
+	// SlowCallDurationThreshold is the threshold calls are classified with unless they set their own,
+	// it changes with the recent latency when an AdaptiveSlowCallThreshold is configured
+	SlowCallDurationThreshold time.Duration
+}
+
+// setPercents derives the Percent fields from the rates
+func (r *CallRates) setPercents() {
+	r.SuccessRatePercent = r.SuccessRate * 100
+	r.FailureRatePercent = r.FailureRate * 100
+	r.SlowCallRatePercent = r.SlowCallRate * 100
+}
+
+// MetricsSnapshot is a point-in-time view of a circuit breaker's internal state
+type MetricsSnapshot struct {
+	Name           string
+	State          State
+	TransitionTime time.Time
+
+	// TimeSinceTransition is the time elapsed since the last state transition
+	TimeSinceTransition time.Duration
+
+	// BufferedCalls is the number of calls currently recorded in the window
+	BufferedCalls int
+
+	// FailureRate and SlowCallRate are ratios from 0 to 1, the Percent fields the same in percentage
+	FailureRate         float64
+	SlowCallRate        float64
+	FailureRatePercent  float64
+	SlowCallRatePercent float64
+
+	// HalfOpenPermitsAvailable is the number of calls still permitted while half-open, zero in any other state
+	HalfOpenPermitsAvailable int
+
+	// NotPermittedCalls is the total number of calls rejected since the circuit breaker was created
+	NotPermittedCalls int64
+
+	// WaitDurationInOpenState is the wait of the current or, when not open, the last opening
+	WaitDurationInOpenState time.Duration
 }
 
 // Metrics defines the interface for circuit breaker instrumentation
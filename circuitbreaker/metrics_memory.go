@@ -0,0 +1,124 @@
+package circuitbreaker
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+var _ Metrics = (*InMemoryMetrics)(nil)
+
+// BreakerStats contains the metrics recorded for a single circuit breaker
+type BreakerStats struct {
+	State            State
+	StateTransitions int64
+
+	Calls              map[CallOutcome]int64
+	CallsDurationTotal time.Duration
+
+	Rejections map[State]int64
+
+	LastRates CallRates
+}
+
+func (s *BreakerStats) clone() BreakerStats {
+	c := *s
+	c.Calls = maps.Clone(s.Calls)
+	c.Rejections = maps.Clone(s.Rejections)
+	return c
+}
+
+// InMemoryMetrics keeps metrics for every circuit breaker in memory, keyed by circuit breaker name.
+// It is mainly useful for tests and debugging.
+type InMemoryMetrics struct {
+	mu       sync.Mutex
+	breakers map[string]*BreakerStats
+}
+
+func NewInMemoryMetrics() *InMemoryMetrics {
+	return &InMemoryMetrics{
+		breakers: make(map[string]*BreakerStats),
+	}
+}
+
+func (m *InMemoryMetrics) statsUnsafe(name string) *BreakerStats {
+	stats, ok := m.breakers[name]
+	if !ok {
+		stats = &BreakerStats{
+			Calls:      make(map[CallOutcome]int64),
+			Rejections: make(map[State]int64),
+		}
+		m.breakers[name] = stats
+	}
+
+	return stats
+}
+
+func (m *InMemoryMetrics) RecordStateTransition(_ context.Context, transition StateTransition) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.statsUnsafe(transition.Name)
+	stats.State = transition.ToState
+	stats.StateTransitions++
+}
+
+func (m *InMemoryMetrics) RecordCallResult(_ context.Context, result CallResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.statsUnsafe(result.Name)
+	stats.Calls[result.Outcome]++
+	stats.CallsDurationTotal += result.Duration
+}
+
+func (m *InMemoryMetrics) RecordCallRejection(_ context.Context, rejection CallRejection) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.statsUnsafe(rejection.Name)
+	stats.Rejections[rejection.State]++
+}
+
+func (m *InMemoryMetrics) RecordCallRates(_ context.Context, rates CallRates) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.statsUnsafe(rates.Name)
+	stats.LastRates = rates
+}
+
+// GetMetrics returns a copy of the metrics recorded for the named circuit breaker
+func (m *InMemoryMetrics) GetMetrics(name string) (BreakerStats, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.breakers[name]
+	if !ok {
+		return BreakerStats{}, false
+	}
+
+	return stats.clone(), true
+}
+
+// GetAllMetrics returns a copy of the metrics recorded for every circuit breaker
+func (m *InMemoryMetrics) GetAllMetrics() map[string]BreakerStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	all := make(map[string]BreakerStats, len(m.breakers))
+	for name, stats := range m.breakers {
+		all[name] = stats.clone()
+	}
+
+	return all
+}
+
+// Reset discards all recorded metrics
+func (m *InMemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.breakers = make(map[string]*BreakerStats)
+}
@@ -0,0 +1,190 @@
+package circuitbreaker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	_ Metrics = (*multiMetrics)(nil)
+	_ Metrics = (*AsyncMetrics)(nil)
+)
+
+// multiMetrics fans out every event to a list of sinks
+type multiMetrics struct {
+	sinks []Metrics
+}
+
+// MultiMetrics returns a Metrics implementation that forwards every event to all the given sinks.
+// A panic in one sink is recovered and does not prevent the remaining sinks from being called,
+// see SetMetricsPanicHandler.
+func MultiMetrics(sinks ...Metrics) Metrics {
+	filtered := make([]Metrics, 0, len(sinks))
+	for _, sink := range sinks {
+		if sink != nil {
+			filtered = append(filtered, sink)
+		}
+	}
+
+	return &multiMetrics{sinks: filtered}
+}
+
+func (m *multiMetrics) RecordStateTransition(ctx context.Context, transition StateTransition) {
+	for _, sink := range m.sinks {
+		safeRecord(func() { sink.RecordStateTransition(ctx, transition) })
+	}
+}
+
+func (m *multiMetrics) RecordCallResult(ctx context.Context, result CallResult) {
+	for _, sink := range m.sinks {
+		safeRecord(func() { sink.RecordCallResult(ctx, result) })
+	}
+}
+
+func (m *multiMetrics) RecordCallRejection(ctx context.Context, rejection CallRejection) {
+	for _, sink := range m.sinks {
+		safeRecord(func() { sink.RecordCallRejection(ctx, rejection) })
+	}
+}
+
+func (m *multiMetrics) RecordCallRates(ctx context.Context, rates CallRates) {
+	for _, sink := range m.sinks {
+		safeRecord(func() { sink.RecordCallRates(ctx, rates) })
+	}
+}
+
+var (
+	_metricsPanicHandler atomic.Pointer[func(recovered any)]
+	_metricsPanics       atomic.Uint64
+)
+
+// SetMetricsPanicHandler sets a function called with the value recovered from a sink of
+// MultiMetrics or AsyncMetrics that panicked, e.g. to log it. A nil handler removes it.
+func SetMetricsPanicHandler(handler func(recovered any)) {
+	if handler == nil {
+		_metricsPanicHandler.Store(nil)
+		return
+	}
+
+	_metricsPanicHandler.Store(&handler)
+}
+
+// MetricsPanics returns the number of panics recovered from sinks of MultiMetrics and AsyncMetrics
+func MetricsPanics() uint64 {
+	return _metricsPanics.Load()
+}
+
+// safeRecord runs fn and recovers any panic so a faulty sink cannot break the caller, the panic
+// is counted and passed to the handler set with SetMetricsPanicHandler
+func safeRecord(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			_metricsPanics.Add(1)
+			if handler := _metricsPanicHandler.Load(); handler != nil {
+				(*handler)(r)
+			}
+		}
+	}()
+
+	fn()
+}
+
+// AsyncMetrics forwards events to a sink from a background goroutine through a bounded buffer.
+// When the buffer is full, events are dropped and counted instead of blocking the caller.
+type AsyncMetrics struct {
+	sink   Metrics
+	events chan func()
+	done   chan struct{}
+
+	mu      sync.RWMutex
+	closed  bool
+	dropped atomic.Uint64
+}
+
+// NewAsyncMetrics starts a background worker that forwards events to sink.
+// bufferSize is the number of events that can be queued before new events are dropped.
+func NewAsyncMetrics(sink Metrics, bufferSize int) *AsyncMetrics {
+	if sink == nil {
+		sink = &NoopMetrics{}
+	}
+
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+
+	m := &AsyncMetrics{
+		sink:   sink,
+		events: make(chan func(), bufferSize),
+		done:   make(chan struct{}),
+	}
+
+	go m.run()
+
+	return m
+}
+
+func (m *AsyncMetrics) run() {
+	defer close(m.done)
+
+	for event := range m.events {
+		safeRecord(event)
+	}
+}
+
+func (m *AsyncMetrics) enqueue(event func()) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		m.dropped.Add(1)
+		return
+	}
+
+	select {
+	case m.events <- event:
+	default:
+		m.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of events dropped because the buffer was full or the sink was closed
+func (m *AsyncMetrics) Dropped() uint64 {
+	return m.dropped.Load()
+}
+
+// Close stops accepting new events and blocks until all queued events have been delivered
+func (m *AsyncMetrics) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		<-m.done
+		return
+	}
+
+	m.closed = true
+	close(m.events)
+	m.mu.Unlock()
+
+	<-m.done
+}
+
+func (m *AsyncMetrics) RecordStateTransition(ctx context.Context, transition StateTransition) {
+	ctx = context.WithoutCancel(ctx)
+	m.enqueue(func() { m.sink.RecordStateTransition(ctx, transition) })
+}
+
+func (m *AsyncMetrics) RecordCallResult(ctx context.Context, result CallResult) {
+	ctx = context.WithoutCancel(ctx)
+	m.enqueue(func() { m.sink.RecordCallResult(ctx, result) })
+}
+
+func (m *AsyncMetrics) RecordCallRejection(ctx context.Context, rejection CallRejection) {
+	ctx = context.WithoutCancel(ctx)
+	m.enqueue(func() { m.sink.RecordCallRejection(ctx, rejection) })
+}
+
+func (m *AsyncMetrics) RecordCallRates(ctx context.Context, rates CallRates) {
+	ctx = context.WithoutCancel(ctx)
+	m.enqueue(func() { m.sink.RecordCallRates(ctx, rates) })
+}
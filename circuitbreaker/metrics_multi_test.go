@@ -0,0 +1,144 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+type panickingMetrics struct {
+	circuitbreaker.NoopMetrics
+}
+
+func (p *panickingMetrics) RecordCallResult(_ context.Context, _ circuitbreaker.CallResult) {
+	panic("faulty exporter")
+}
+
+type panickingInMemoryMetrics struct {
+	*circuitbreaker.InMemoryMetrics
+}
+
+func (p *panickingInMemoryMetrics) RecordCallResult(_ context.Context, _ circuitbreaker.CallResult) {
+	panic("faulty exporter")
+}
+
+type blockingMetrics struct {
+	circuitbreaker.NoopMetrics
+	release chan struct{}
+}
+
+func (b *blockingMetrics) RecordCallResult(_ context.Context, _ circuitbreaker.CallResult) {
+	<-b.release
+}
+
+func capturePanics(t *testing.T) *[]any {
+	t.Helper()
+
+	var recovered []any
+	circuitbreaker.SetMetricsPanicHandler(func(r any) { recovered = append(recovered, r) })
+	t.Cleanup(func() { circuitbreaker.SetMetricsPanicHandler(nil) })
+
+	return &recovered
+}
+
+func TestMultiMetrics_FansOut(t *testing.T) {
+	first := circuitbreaker.NewInMemoryMetrics()
+	second := circuitbreaker.NewInMemoryMetrics()
+
+	cb := circuitbreaker.New("fan-out", circuitbreaker.WithMetrics(circuitbreaker.MultiMetrics(first, nil, second)))
+	require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+	require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, failingCall), errDependency)
+
+	for _, sink := range []*circuitbreaker.InMemoryMetrics{first, second} {
+		stats, ok := sink.GetMetrics("fan-out")
+		require.True(t, ok)
+		require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeSuccess])
+		require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeFailure])
+		require.Equal(t, 2, stats.LastRates.TotalCalls)
+	}
+}
+
+func TestMultiMetrics_IsolatesPanics(t *testing.T) {
+	recovered := capturePanics(t)
+	panics := circuitbreaker.MetricsPanics()
+
+	first := circuitbreaker.NewInMemoryMetrics()
+	second := circuitbreaker.NewInMemoryMetrics()
+	m := circuitbreaker.MultiMetrics(first, &panickingMetrics{}, second)
+
+	require.NotPanics(
+		t, func() {
+			m.RecordCallResult(
+				context.Background(), circuitbreaker.CallResult{Name: "isolated", Outcome: circuitbreaker.OutcomeSuccess},
+			)
+		},
+	)
+
+	for _, sink := range []*circuitbreaker.InMemoryMetrics{first, second} {
+		stats, ok := sink.GetMetrics("isolated")
+		require.True(t, ok)
+		require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeSuccess])
+	}
+
+	require.Equal(t, []any{"faulty exporter"}, *recovered)
+	require.Equal(t, panics+1, circuitbreaker.MetricsPanics())
+}
+
+func TestAsyncMetrics_DeliversOnClose(t *testing.T) {
+	sink := circuitbreaker.NewInMemoryMetrics()
+	m := circuitbreaker.NewAsyncMetrics(sink, 16)
+
+	for i := 0; i < 10; i++ {
+		m.RecordCallResult(
+			context.Background(), circuitbreaker.CallResult{Name: "async", Outcome: circuitbreaker.OutcomeFailure},
+		)
+	}
+	m.Close()
+
+	stats, ok := sink.GetMetrics("async")
+	require.True(t, ok)
+	require.Equal(t, int64(10), stats.Calls[circuitbreaker.OutcomeFailure])
+	require.Zero(t, m.Dropped())
+}
+
+func TestAsyncMetrics_DropsWhenFull(t *testing.T) {
+	sink := &blockingMetrics{release: make(chan struct{})}
+	m := circuitbreaker.NewAsyncMetrics(sink, 1)
+
+	// the first event is picked up by the worker and blocks it, the second fills the buffer
+	m.RecordCallResult(context.Background(), circuitbreaker.CallResult{})
+	require.Eventually(
+		t, func() bool {
+			m.RecordCallResult(context.Background(), circuitbreaker.CallResult{})
+			return m.Dropped() > 0
+		}, time.Second, time.Millisecond,
+	)
+
+	close(sink.release)
+	m.Close()
+
+	dropped := m.Dropped()
+	m.RecordCallResult(context.Background(), circuitbreaker.CallResult{})
+	require.Equal(t, dropped+1, m.Dropped())
+}
+
+func TestAsyncMetrics_IsolatesPanics(t *testing.T) {
+	recovered := capturePanics(t)
+
+	memory := circuitbreaker.NewInMemoryMetrics()
+	m := circuitbreaker.NewAsyncMetrics(&panickingInMemoryMetrics{InMemoryMetrics: memory}, 4)
+
+	m.RecordCallResult(context.Background(), circuitbreaker.CallResult{Name: "async"})
+	m.RecordCallRates(context.Background(), circuitbreaker.CallRates{Name: "async", TotalCalls: 1})
+	m.Close()
+
+	// the worker survived the panic and delivered the event queued after it
+	stats, ok := memory.GetMetrics("async")
+	require.True(t, ok)
+	require.Equal(t, 1, stats.LastRates.TotalCalls)
+	require.Equal(t, []any{"faulty exporter"}, *recovered)
+}
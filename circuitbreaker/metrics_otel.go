@@ -12,7 +12,8 @@ import (
 // Metrics:
 // circuitbreaker_calls_total (Counter) - Total number of calls through the circuit breaker
 // * name (string) - The name of the circuit breaker
-// * outcome (string) - The outcome of the call ("success", "failure", "slow_success", "slow_failure")
+// * outcome (string) - The outcome of the call ("success", "failure", "slow_success", "slow_failure",
+// "timeout", "ignored", "cancelled")
 //
 // circuitbreaker_calls_duration_milliseconds (Histogram) - Duration of calls in milliseconds
 // * name (string) - The name of the circuit breaker
@@ -27,14 +28,30 @@ import (
 // * from_state (string) - The previous state
 // * to_state (string) - The new state
 //
-// circuitbreaker_state (Gauge) - Current state of the circuit breaker (0=closed, 1=half_open, 2=open, 3=metrics_only)
+// circuitbreaker_state (Gauge) - Current state of the circuit breaker, 1 for the active state and 0 otherwise
 // * name (string) - The name of the circuit breaker
+// * state (string) - The state ("closed", "half_open", "open", "metrics_only")
 //
 // circuitbreaker_failure_rate (Gauge) - Current failure rate percentage
 // * name (string) - The name of the circuit breaker
 //
 // circuitbreaker_slow_call_rate (Gauge) - Current slow call rate percentage
 // * name (string) - The name of the circuit breaker
+//
+// circuitbreaker_open_wait_duration_milliseconds (Gauge) - Wait of the current or last opening in milliseconds
+// * name (string) - The name of the circuit breaker
+//
+// When created with WithRegistry, the gauges above are observed from the registered circuit breakers at
+// collection time instead of being recorded on transitions and calls, and the following metrics are added:
+//
+// circuitbreaker_buffered_calls (Gauge) - Number of calls currently recorded in the window
+// * name (string) - The name of the circuit breaker
+//
+// circuitbreaker_not_permitted_calls (Counter) - Total number of calls rejected since the circuit breaker was created
+// * name (string) - The name of the circuit breaker
+//
+// circuitbreaker_half_open_permits_available (Gauge) - Number of calls still permitted while half-open
+// * name (string) - The name of the circuit breaker
 
 const (
 	instrumentationName    = "github.com/hugolhafner/dskit/circuitbreaker"
@@ -58,16 +75,22 @@ type OTelMetrics struct {
 	rejectionsTotal metric.Int64Counter
 
 	stateTransitionsTotal metric.Int64Counter
-	currentState          metric.Int64Gauge
 
-	failureRate  metric.Float64Gauge
-	slowCallRate metric.Float64Gauge
+	// currentState, failureRate, slowCallRate and openWaitDuration are nil when state is observed from a registry
+	currentState     metric.Int64Gauge
+	failureRate      metric.Float64Gauge
+	slowCallRate     metric.Float64Gauge
+	openWaitDuration metric.Float64Gauge
+
+	// registration is the callback observing the registry, nil when no registry is configured
+	registration metric.Registration
 }
 
 type OTelConfig struct {
 	MeterProvider metric.MeterProvider
 	MetricPrefix  string
 	Attributes    []attribute.KeyValue
+	Registry      *Registry
 }
 
 type OTelOption func(*OTelConfig)
@@ -92,6 +115,14 @@ func WithAttributes(attrs []attribute.KeyValue) OTelOption {
 	}
 }
 
+// WithRegistry observes the state, rates and permits of every circuit breaker in the registry
+// at collection time, so gauges stay current when traffic stops
+func WithRegistry(registry *Registry) OTelOption {
+	return func(cfg *OTelConfig) {
+		cfg.Registry = registry
+	}
+}
+
 func NewOTelMetrics(opts ...OTelOption) (*OTelMetrics, error) {
 	cfg := &OTelConfig{
 		MeterProvider: otel.GetMeterProvider(),
@@ -142,7 +173,23 @@ func NewOTelMetrics(opts ...OTelOption) (*OTelMetrics, error) {
 		return nil, fmt.Errorf("failed to create state_transitions_total counter: %w", err)
 	}
 
-	currentState, err := meter.Int64Gauge(
+	m := &OTelMetrics{
+		callsTotal:            callsTotal,
+		callsDuration:         callsDuration,
+		rejectionsTotal:       rejectionsTotal,
+		stateTransitionsTotal: stateTransitionsTotal,
+	}
+
+	if cfg.Registry != nil {
+		m.registration, err = registerObservableGauges(meter, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return m, nil
+	}
+
+	m.currentState, err = meter.Int64Gauge(
 		cfg.MetricPrefix+"state",
 		metric.WithDescription("Current state of the circuit breaker"),
 	)
@@ -150,7 +197,7 @@ func NewOTelMetrics(opts ...OTelOption) (*OTelMetrics, error) {
 		return nil, fmt.Errorf("failed to create state gauge: %w", err)
 	}
 
-	failureRate, err := meter.Float64Gauge(
+	m.failureRate, err = meter.Float64Gauge(
 		cfg.MetricPrefix+"failure_rate",
 		metric.WithDescription("Current failure rate percentage"),
 		metric.WithUnit(unitPercent),
@@ -159,7 +206,7 @@ func NewOTelMetrics(opts ...OTelOption) (*OTelMetrics, error) {
 		return nil, fmt.Errorf("failed to create failure_rate gauge: %w", err)
 	}
 
-	slowCallRate, err := meter.Float64Gauge(
+	m.slowCallRate, err = meter.Float64Gauge(
 		cfg.MetricPrefix+"slow_call_rate",
 		metric.WithDescription("Current slow call rate percentage"),
 		metric.WithUnit(unitPercent),
@@ -168,15 +215,130 @@ func NewOTelMetrics(opts ...OTelOption) (*OTelMetrics, error) {
 		return nil, fmt.Errorf("failed to create slow_call_rate gauge: %w", err)
 	}
 
-	return &OTelMetrics{
-		callsTotal:            callsTotal,
-		callsDuration:         callsDuration,
-		rejectionsTotal:       rejectionsTotal,
-		stateTransitionsTotal: stateTransitionsTotal,
-		currentState:          currentState,
-		failureRate:           failureRate,
-		slowCallRate:          slowCallRate,
-	}, nil
+	m.openWaitDuration, err = meter.Float64Gauge(
+		cfg.MetricPrefix+"open_wait_duration_milliseconds",
+		metric.WithDescription("Wait of the current or last opening in milliseconds"),
+		metric.WithUnit(unitMilliseconds),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create open_wait_duration_milliseconds gauge: %w", err)
+	}
+
+	return m, nil
+}
+
+func registerObservableGauges(meter metric.Meter, cfg *OTelConfig) (metric.Registration, error) {
+	currentState, err := meter.Int64ObservableGauge(
+		cfg.MetricPrefix+"state",
+		metric.WithDescription("Current state of the circuit breaker"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state gauge: %w", err)
+	}
+
+	failureRate, err := meter.Float64ObservableGauge(
+		cfg.MetricPrefix+"failure_rate",
+		metric.WithDescription("Current failure rate percentage"),
+		metric.WithUnit(unitPercent),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create failure_rate gauge: %w", err)
+	}
+
+	slowCallRate, err := meter.Float64ObservableGauge(
+		cfg.MetricPrefix+"slow_call_rate",
+		metric.WithDescription("Current slow call rate percentage"),
+		metric.WithUnit(unitPercent),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create slow_call_rate gauge: %w", err)
+	}
+
+	openWaitDuration, err := meter.Float64ObservableGauge(
+		cfg.MetricPrefix+"open_wait_duration_milliseconds",
+		metric.WithDescription("Wait of the current or last opening in milliseconds"),
+		metric.WithUnit(unitMilliseconds),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create open_wait_duration_milliseconds gauge: %w", err)
+	}
+
+	bufferedCalls, err := meter.Int64ObservableGauge(
+		cfg.MetricPrefix+"buffered_calls",
+		metric.WithDescription("Number of calls currently recorded in the window"),
+		metric.WithUnit(unitCall),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create buffered_calls gauge: %w", err)
+	}
+
+	notPermittedCalls, err := meter.Int64ObservableCounter(
+		cfg.MetricPrefix+"not_permitted_calls",
+		metric.WithDescription("Total number of calls rejected since the circuit breaker was created"),
+		metric.WithUnit(unitCall),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create not_permitted_calls counter: %w", err)
+	}
+
+	halfOpenPermits, err := meter.Int64ObservableGauge(
+		cfg.MetricPrefix+"half_open_permits_available",
+		metric.WithDescription("Number of calls still permitted while half-open"),
+		metric.WithUnit(unitCall),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create half_open_permits_available gauge: %w", err)
+	}
+
+	registration, err := meter.RegisterCallback(
+		func(_ context.Context, o metric.Observer) error {
+			for _, cb := range cfg.Registry.All() {
+				snapshot := cb.Metrics()
+				nameAttr := attribute.String("name", snapshot.Name)
+
+				for state := StateClosed; state <= StateMetricsOnly; state++ {
+					var value int64
+					if state == snapshot.State {
+						value = 1
+					}
+
+					o.ObserveInt64(
+						currentState, value,
+						metric.WithAttributes(nameAttr, attribute.String("state", stateString(state))),
+					)
+				}
+
+				o.ObserveFloat64(failureRate, snapshot.FailureRatePercent, metric.WithAttributes(nameAttr))
+				o.ObserveFloat64(slowCallRate, snapshot.SlowCallRatePercent, metric.WithAttributes(nameAttr))
+				o.ObserveFloat64(
+					openWaitDuration, float64(snapshot.WaitDurationInOpenState.Milliseconds()),
+					metric.WithAttributes(nameAttr),
+				)
+				o.ObserveInt64(bufferedCalls, int64(snapshot.BufferedCalls), metric.WithAttributes(nameAttr))
+				o.ObserveInt64(notPermittedCalls, snapshot.NotPermittedCalls, metric.WithAttributes(nameAttr))
+				o.ObserveInt64(
+					halfOpenPermits, int64(snapshot.HalfOpenPermitsAvailable), metric.WithAttributes(nameAttr),
+				)
+			}
+
+			return nil
+		},
+		currentState, failureRate, slowCallRate, openWaitDuration, bufferedCalls, notPermittedCalls, halfOpenPermits,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register gauge callback: %w", err)
+	}
+
+	return registration, nil
+}
+
+// Close unregisters the registry callback, it is a no-op when no registry is configured
+func (m *OTelMetrics) Close() error {
+	if m.registration == nil {
+		return nil
+	}
+
+	return m.registration.Unregister()
 }
 
 func MustNewOTelMetrics(opts ...OTelOption) *OTelMetrics {
@@ -189,18 +351,7 @@ func MustNewOTelMetrics(opts ...OTelOption) *OTelMetrics {
 }
 
 func outcomeString(outcome CallOutcome) string {
-	switch outcome {
-	case OutcomeSuccess:
-		return "success"
-	case OutcomeFailure:
-		return "failure"
-	case OutcomeSlowSuccess:
-		return "slow_success"
-	case OutcomeSlowFailure:
-		return "slow_failure"
-	default:
-		return "unknown"
-	}
+	return outcome.String()
 }
 
 func stateString(state State) string {
@@ -227,6 +378,17 @@ func (m *OTelMetrics) RecordStateTransition(ctx context.Context, transition Stat
 
 	m.stateTransitionsTotal.Add(ctx, 1, metric.WithAttributes(attrs...))
 
+	if m.currentState == nil {
+		return
+	}
+
+	if transition.ToState == StateOpen {
+		m.openWaitDuration.Record(
+			ctx, float64(transition.WaitDurationInOpenState.Milliseconds()),
+			metric.WithAttributes(attribute.String("name", transition.Name)),
+		)
+	}
+
 	for state := StateClosed; state <= StateMetricsOnly; state++ {
 		var value int64
 		if state == transition.ToState {
@@ -263,8 +425,12 @@ func (m *OTelMetrics) RecordCallRejection(ctx context.Context, rejection CallRej
 }
 
 func (m *OTelMetrics) RecordCallRates(ctx context.Context, rates CallRates) {
+	if m.failureRate == nil {
+		return
+	}
+
 	nameAttr := attribute.String("name", rates.Name)
 
-	m.failureRate.Record(ctx, rates.FailureRate, metric.WithAttributes(nameAttr))
-	m.slowCallRate.Record(ctx, rates.SlowCallRate, metric.WithAttributes(nameAttr))
+	m.failureRate.Record(ctx, rates.FailureRatePercent, metric.WithAttributes(nameAttr))
+	m.slowCallRate.Record(ctx, rates.SlowCallRatePercent, metric.WithAttributes(nameAttr))
 }
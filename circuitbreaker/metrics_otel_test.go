@@ -0,0 +1,131 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+func gaugeValue[N int64 | float64](t *testing.T, rm metricdata.ResourceMetrics, name string, attrs ...attribute.KeyValue) N {
+	t.Helper()
+
+	want := attribute.NewSet(attrs...)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+
+			gauge, ok := m.Data.(metricdata.Gauge[N])
+			require.True(t, ok, "metric %s is not a gauge", name)
+
+			for _, dp := range gauge.DataPoints {
+				if dp.Attributes.Equals(&want) {
+					return dp.Value
+				}
+			}
+		}
+	}
+
+	require.Failf(t, "gauge not found", "%s %v", name, attrs)
+	return 0
+}
+
+func counterValue(t *testing.T, rm metricdata.ResourceMetrics, name string, attrs ...attribute.KeyValue) int64 {
+	t.Helper()
+
+	want := attribute.NewSet(attrs...)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			require.True(t, ok, "metric %s is not a counter", name)
+
+			for _, dp := range sum.DataPoints {
+				if dp.Attributes.Equals(&want) {
+					return dp.Value
+				}
+			}
+		}
+	}
+
+	return 0
+}
+
+func TestOTelMetrics_OutcomeLabels(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	metrics := circuitbreaker.MustNewOTelMetrics(circuitbreaker.WithMeterProvider(provider))
+	cb := circuitbreaker.New("labels", circuitbreaker.WithMetrics(metrics))
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = circuitbreaker.Do(cancelled, cb, succeedingCall)
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now())
+	defer cancelExpired()
+	_ = circuitbreaker.Do(context.Background(), cb, func(context.Context) error { return expired.Err() })
+
+	_ = circuitbreaker.Do(context.Background(), cb, failingCall)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	name := attribute.String("name", "labels")
+	for _, outcome := range []string{"cancelled", "timeout", "failure"} {
+		require.Equal(
+			t, int64(1), counterValue(t, rm, "circuitbreaker_calls_total", name, attribute.String("outcome", outcome)),
+			outcome,
+		)
+	}
+}
+
+func TestOTelMetrics_ObservesRegistryAtCollection(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	registry := circuitbreaker.NewRegistry()
+	metrics := circuitbreaker.MustNewOTelMetrics(
+		circuitbreaker.WithMeterProvider(provider),
+		circuitbreaker.WithRegistry(registry),
+	)
+	t.Cleanup(func() { require.NoError(t, metrics.Close()) })
+
+	// a breaker that never transitioned or saw traffic must still be reported
+	registry.GetOrCreate("idle", circuitbreaker.WithMetrics(metrics))
+
+	cb := registry.GetOrCreate(
+		"orders",
+		circuitbreaker.WithMetrics(metrics),
+		circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)),
+		circuitbreaker.WithMinimumNumberOfCalls(2),
+	)
+	for i := 0; i < 3; i++ {
+		_ = circuitbreaker.Do(context.Background(), cb, failingCall)
+	}
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	orders := attribute.String("name", "orders")
+	idle := attribute.String("name", "idle")
+
+	require.Equal(t, int64(1), gaugeValue[int64](t, rm, "circuitbreaker_state", orders, attribute.String("state", "open")))
+	require.Equal(t, int64(1), gaugeValue[int64](t, rm, "circuitbreaker_state", idle, attribute.String("state", "closed")))
+	require.Equal(t, int64(1), counterValue(t, rm, "circuitbreaker_not_permitted_calls", orders))
+	require.Equal(t, int64(0), gaugeValue[int64](t, rm, "circuitbreaker_half_open_permits_available", orders))
+	require.Equal(t, int64(0), gaugeValue[int64](t, rm, "circuitbreaker_buffered_calls", idle))
+	require.Equal(t, 0.0, gaugeValue[float64](t, rm, "circuitbreaker_failure_rate", idle))
+	require.Equal(t, 60000.0, gaugeValue[float64](t, rm, "circuitbreaker_open_wait_duration_milliseconds", orders))
+}
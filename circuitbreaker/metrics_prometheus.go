@@ -0,0 +1,297 @@
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	_ Metrics              = (*PrometheusMetrics)(nil)
+	_ prometheus.Collector = (*registryCollector)(nil)
+)
+
+// PrometheusMetrics exposes the same metric names and labels as OTelMetrics using the
+// Prometheus client directly, for services that do not run an OpenTelemetry SDK.
+//
+// When a Registry is provided with WithPrometheusRegistry, the state and rate gauges are
+// read from the registered circuit breakers at scrape time instead of being updated on
+// transitions and calls.
+type PrometheusMetrics struct {
+	callsTotal    *prometheus.CounterVec
+	callsDuration *prometheus.HistogramVec
+
+	rejectionsTotal *prometheus.CounterVec
+
+	stateTransitionsTotal *prometheus.CounterVec
+
+	// currentState, failureRate, slowCallRate and openWaitDuration are nil when state is collected from a registry
+	currentState     *prometheus.GaugeVec
+	failureRate      *prometheus.GaugeVec
+	slowCallRate     *prometheus.GaugeVec
+	openWaitDuration *prometheus.GaugeVec
+}
+
+type PrometheusConfig struct {
+	Registerer   prometheus.Registerer
+	MetricPrefix string
+	ConstLabels  prometheus.Labels
+	Registry     *Registry
+}
+
+type PrometheusOption func(*PrometheusConfig)
+
+func WithPrometheusRegisterer(registerer prometheus.Registerer) PrometheusOption {
+	return func(cfg *PrometheusConfig) {
+		cfg.Registerer = registerer
+	}
+}
+
+func WithPrometheusMetricPrefix(prefix string) PrometheusOption {
+	return func(cfg *PrometheusConfig) {
+		cfg.MetricPrefix = prefix
+	}
+}
+
+func WithPrometheusConstLabels(labels prometheus.Labels) PrometheusOption {
+	return func(cfg *PrometheusConfig) {
+		cfg.ConstLabels = maps.Clone(labels)
+	}
+}
+
+// WithPrometheusRegistry reads the state and rate gauges from the registry at scrape time
+func WithPrometheusRegistry(registry *Registry) PrometheusOption {
+	return func(cfg *PrometheusConfig) {
+		cfg.Registry = registry
+	}
+}
+
+func NewPrometheusMetrics(opts ...PrometheusOption) (*PrometheusMetrics, error) {
+	cfg := &PrometheusConfig{
+		Registerer:   prometheus.DefaultRegisterer,
+		MetricPrefix: "circuitbreaker_",
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	m := &PrometheusMetrics{
+		callsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        cfg.MetricPrefix + "calls_total",
+				Help:        "Total number of calls through the circuit breaker",
+				ConstLabels: cfg.ConstLabels,
+			}, []string{"name", "outcome"},
+		),
+		callsDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        cfg.MetricPrefix + "calls_duration_milliseconds",
+				Help:        "Duration of calls in milliseconds",
+				ConstLabels: cfg.ConstLabels,
+				Buckets:     []float64{0, 1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000},
+			}, []string{"name", "outcome"},
+		),
+		rejectionsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        cfg.MetricPrefix + "rejections_total",
+				Help:        "Total number of rejected calls",
+				ConstLabels: cfg.ConstLabels,
+			}, []string{"name", "state"},
+		),
+		stateTransitionsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        cfg.MetricPrefix + "state_transitions_total",
+				Help:        "Total number of state transitions",
+				ConstLabels: cfg.ConstLabels,
+			}, []string{"name", "from_state", "to_state"},
+		),
+	}
+
+	collectors := []prometheus.Collector{m.callsTotal, m.callsDuration, m.rejectionsTotal, m.stateTransitionsTotal}
+
+	if cfg.Registry != nil {
+		collectors = append(collectors, NewPrometheusCollector(cfg.Registry, cfg.MetricPrefix, cfg.ConstLabels))
+	} else {
+		m.currentState = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:        cfg.MetricPrefix + "state",
+				Help:        "Current state of the circuit breaker",
+				ConstLabels: cfg.ConstLabels,
+			}, []string{"name", "state"},
+		)
+		m.failureRate = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:        cfg.MetricPrefix + "failure_rate",
+				Help:        "Current failure rate percentage",
+				ConstLabels: cfg.ConstLabels,
+			}, []string{"name"},
+		)
+		m.slowCallRate = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:        cfg.MetricPrefix + "slow_call_rate",
+				Help:        "Current slow call rate percentage",
+				ConstLabels: cfg.ConstLabels,
+			}, []string{"name"},
+		)
+
+		m.openWaitDuration = prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:        cfg.MetricPrefix + "open_wait_duration_milliseconds",
+				Help:        "Wait of the current or last opening in milliseconds",
+				ConstLabels: cfg.ConstLabels,
+			}, []string{"name"},
+		)
+
+		collectors = append(collectors, m.currentState, m.failureRate, m.slowCallRate, m.openWaitDuration)
+	}
+
+	for _, c := range collectors {
+		if err := cfg.Registerer.Register(c); err != nil {
+			return nil, fmt.Errorf("failed to register prometheus collector: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+func MustNewPrometheusMetrics(opts ...PrometheusOption) *PrometheusMetrics {
+	m, err := NewPrometheusMetrics(opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+func (m *PrometheusMetrics) RecordStateTransition(_ context.Context, transition StateTransition) {
+	m.stateTransitionsTotal.WithLabelValues(
+		transition.Name, stateString(transition.FromState), stateString(transition.ToState),
+	).Inc()
+
+	if m.currentState == nil {
+		return
+	}
+
+	if transition.ToState == StateOpen {
+		m.openWaitDuration.WithLabelValues(transition.Name).Set(float64(transition.WaitDurationInOpenState.Milliseconds()))
+	}
+
+	for state := StateClosed; state <= StateMetricsOnly; state++ {
+		var value float64
+		if state == transition.ToState {
+			value = 1
+		}
+
+		m.currentState.WithLabelValues(transition.Name, stateString(state)).Set(value)
+	}
+}
+
+func (m *PrometheusMetrics) RecordCallResult(_ context.Context, result CallResult) {
+	outcome := outcomeString(result.Outcome)
+
+	m.callsTotal.WithLabelValues(result.Name, outcome).Inc()
+	m.callsDuration.WithLabelValues(result.Name, outcome).Observe(float64(result.Duration.Milliseconds()))
+}
+
+func (m *PrometheusMetrics) RecordCallRejection(_ context.Context, rejection CallRejection) {
+	m.rejectionsTotal.WithLabelValues(rejection.Name, stateString(rejection.State)).Inc()
+}
+
+func (m *PrometheusMetrics) RecordCallRates(_ context.Context, rates CallRates) {
+	if m.failureRate == nil {
+		return
+	}
+
+	m.failureRate.WithLabelValues(rates.Name).Set(rates.FailureRatePercent)
+	m.slowCallRate.WithLabelValues(rates.Name).Set(rates.SlowCallRatePercent)
+}
+
+// registryCollector reads the live state of every circuit breaker in a registry at scrape time
+type registryCollector struct {
+	registry *Registry
+
+	state            *prometheus.Desc
+	failureRate      *prometheus.Desc
+	slowCallRate     *prometheus.Desc
+	openWaitDuration *prometheus.Desc
+	bufferedCalls    *prometheus.Desc
+
+	notPermittedCalls *prometheus.Desc
+	halfOpenPermits   *prometheus.Desc
+}
+
+// NewPrometheusCollector returns a collector that reports the state, rates, open wait, buffered
+// calls and permits of every circuit breaker in the registry when scraped
+func NewPrometheusCollector(registry *Registry, prefix string, constLabels prometheus.Labels) prometheus.Collector {
+	return &registryCollector{
+		registry: registry,
+		state: prometheus.NewDesc(
+			prefix+"state", "Current state of the circuit breaker", []string{"name", "state"}, constLabels,
+		),
+		failureRate: prometheus.NewDesc(
+			prefix+"failure_rate", "Current failure rate percentage", []string{"name"}, constLabels,
+		),
+		slowCallRate: prometheus.NewDesc(
+			prefix+"slow_call_rate", "Current slow call rate percentage", []string{"name"}, constLabels,
+		),
+		openWaitDuration: prometheus.NewDesc(
+			prefix+"open_wait_duration_milliseconds", "Wait of the current or last opening in milliseconds",
+			[]string{"name"}, constLabels,
+		),
+		bufferedCalls: prometheus.NewDesc(
+			prefix+"buffered_calls", "Number of calls currently recorded in the window", []string{"name"}, constLabels,
+		),
+		notPermittedCalls: prometheus.NewDesc(
+			prefix+"not_permitted_calls", "Total number of calls rejected since the circuit breaker was created",
+			[]string{"name"}, constLabels,
+		),
+		halfOpenPermits: prometheus.NewDesc(
+			prefix+"half_open_permits_available", "Number of calls still permitted while half-open",
+			[]string{"name"}, constLabels,
+		),
+	}
+}
+
+func (c *registryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.state
+	ch <- c.failureRate
+	ch <- c.slowCallRate
+	ch <- c.openWaitDuration
+	ch <- c.bufferedCalls
+	ch <- c.notPermittedCalls
+	ch <- c.halfOpenPermits
+}
+
+func (c *registryCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, cb := range c.registry.All() {
+		snapshot := cb.Metrics()
+
+		for state := StateClosed; state <= StateMetricsOnly; state++ {
+			var value float64
+			if state == snapshot.State {
+				value = 1
+			}
+
+			ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, value, snapshot.Name, stateString(state))
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.failureRate, prometheus.GaugeValue, snapshot.FailureRatePercent, snapshot.Name)
+		ch <- prometheus.MustNewConstMetric(c.slowCallRate, prometheus.GaugeValue, snapshot.SlowCallRatePercent, snapshot.Name)
+		ch <- prometheus.MustNewConstMetric(
+			c.openWaitDuration, prometheus.GaugeValue, float64(snapshot.WaitDurationInOpenState.Milliseconds()),
+			snapshot.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.bufferedCalls, prometheus.GaugeValue, float64(snapshot.BufferedCalls), snapshot.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.notPermittedCalls, prometheus.CounterValue, float64(snapshot.NotPermittedCalls), snapshot.Name,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.halfOpenPermits, prometheus.GaugeValue, float64(snapshot.HalfOpenPermitsAvailable), snapshot.Name,
+		)
+	}
+}
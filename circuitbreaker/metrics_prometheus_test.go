@@ -0,0 +1,76 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+func TestPrometheusMetrics_ReadsLiveStateFromRegistry(t *testing.T) {
+	promRegistry := prometheus.NewPedanticRegistry()
+	registry := circuitbreaker.NewRegistry()
+
+	metrics := circuitbreaker.MustNewPrometheusMetrics(
+		circuitbreaker.WithPrometheusRegisterer(promRegistry),
+		circuitbreaker.WithPrometheusRegistry(registry),
+	)
+
+	cb := registry.GetOrCreate(
+		"payments",
+		circuitbreaker.WithMetrics(metrics),
+		circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)),
+		circuitbreaker.WithMinimumNumberOfCalls(2),
+	)
+
+	for i := 0; i < 2; i++ {
+		_ = circuitbreaker.Do(context.Background(), cb, failingCall)
+	}
+	_ = circuitbreaker.Do(context.Background(), cb, failingCall)
+
+	expected := `
+# HELP circuitbreaker_not_permitted_calls Total number of calls rejected since the circuit breaker was created
+# TYPE circuitbreaker_not_permitted_calls counter
+circuitbreaker_not_permitted_calls{name="payments"} 1
+# HELP circuitbreaker_rejections_total Total number of rejected calls
+# TYPE circuitbreaker_rejections_total counter
+circuitbreaker_rejections_total{name="payments",state="open"} 1
+# HELP circuitbreaker_state Current state of the circuit breaker
+# TYPE circuitbreaker_state gauge
+circuitbreaker_state{name="payments",state="closed"} 0
+circuitbreaker_state{name="payments",state="half_open"} 0
+circuitbreaker_state{name="payments",state="metrics_only"} 0
+circuitbreaker_state{name="payments",state="open"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(
+		promRegistry, strings.NewReader(expected),
+		"circuitbreaker_not_permitted_calls", "circuitbreaker_rejections_total", "circuitbreaker_state",
+	))
+
+	count, err := testutil.GatherAndCount(promRegistry, "circuitbreaker_calls_total")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
+
+func TestPrometheusMetrics_PushGaugesWithoutRegistry(t *testing.T) {
+	promRegistry := prometheus.NewPedanticRegistry()
+	metrics := circuitbreaker.MustNewPrometheusMetrics(circuitbreaker.WithPrometheusRegisterer(promRegistry))
+
+	metrics.RecordCallRates(
+		context.Background(), circuitbreaker.CallRates{Name: "search", FailureRate: 0.25, FailureRatePercent: 25},
+	)
+
+	expected := `
+# HELP circuitbreaker_failure_rate Current failure rate percentage
+# TYPE circuitbreaker_failure_rate gauge
+circuitbreaker_failure_rate{name="search"} 25
+`
+	require.NoError(t, testutil.GatherAndCompare(
+		promRegistry, strings.NewReader(expected), "circuitbreaker_failure_rate",
+	))
+}
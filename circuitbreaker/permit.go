@@ -0,0 +1,93 @@
+package circuitbreaker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Permit is a call permitted by TryAcquirePermission. Exactly one of its methods has to be
+// called once the call finished or was abandoned, later calls are ignored.
+type Permit interface {
+	// OnSuccess records a call that succeeded
+	OnSuccess(duration time.Duration)
+
+	// OnError records a call that failed with err, classified like a call through Execute
+	OnError(duration time.Duration, err error)
+
+	// OnResult records a call that returned result, classified with the result predicate
+	OnResult(result any, duration time.Duration)
+
+	// Release gives back a permit of the half-open state when the call is abandoned, so it becomes
+	// available to another call and the abandoned call is no longer recorded. Permits of other
+	// states are not limited, releasing them has no effect and the call can still be recorded.
+	Release()
+}
+
+var _ Permit = (*permit)(nil)
+
+type permit struct {
+	cb    *circuitBreakerImpl
+	lease *halfOpenLease
+	once  sync.Once
+
+	// ctx is the context of the call when the permit was acquired with it, nil otherwise
+	ctx context.Context
+}
+
+// leaseReclaimer is implemented by the permits of this package, so Execute can hand the permit of
+// a cancelled call to another call and still record the outcome of the cancelled one
+type leaseReclaimer interface {
+	reclaim()
+}
+
+func (p *permit) reclaim() {
+	if p.lease != nil {
+		p.cb.reclaimLease(p.lease)
+	}
+}
+
+func (p *permit) OnSuccess(duration time.Duration) {
+	p.once.Do(func() { p.cb.after(p.lease, p.ctx, nil, nil, duration) })
+}
+
+func (p *permit) OnError(duration time.Duration, err error) {
+	p.once.Do(func() { p.cb.after(p.lease, p.ctx, nil, err, duration) })
+}
+
+func (p *permit) OnResult(result any, duration time.Duration) {
+	p.once.Do(func() { p.cb.after(p.lease, p.ctx, result, nil, duration) })
+}
+
+func (p *permit) Release() {
+	if p.lease == nil {
+		return
+	}
+
+	p.once.Do(func() { p.cb.reclaimLease(p.lease) })
+}
+
+// TryAcquirePermission permits a call that cannot be wrapped in a function, e.g. a stream or a
+// call completing in another goroutine. It returns ErrOpenState or ErrHalfOpenState when the
+// call is not permitted.
+func (cb *circuitBreakerImpl) TryAcquirePermission() (Permit, error) {
+	lease, err := cb.before()
+	if err != nil {
+		return nil, err
+	}
+
+	return &permit{cb: cb, lease: lease}, nil
+}
+
+// TryAcquirePermissionContext permits a call made with ctx. A call recorded as failed after ctx
+// was cancelled is OutcomeCancelled, and a threshold set with ContextWithSlowCallDurationThreshold
+// on ctx decides whether the call is slow. Unlike Execute, it does not release the permit when
+// ctx is cancelled.
+func (cb *circuitBreakerImpl) TryAcquirePermissionContext(ctx context.Context) (Permit, error) {
+	lease, err := cb.before()
+	if err != nil {
+		return nil, err
+	}
+
+	return &permit{cb: cb, lease: lease, ctx: ctx}, nil
+}
@@ -0,0 +1,97 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+func TestCircuitBreaker_TryAcquirePermission(t *testing.T) {
+	cb := circuitbreaker.New(
+		"permits",
+		circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)),
+		circuitbreaker.WithMinimumNumberOfCalls(4),
+		circuitbreaker.WithFailOnResultPredicate(func(result any) bool { return result == "degraded" }),
+	)
+
+	for _, record := range []func(circuitbreaker.Permit){
+		func(p circuitbreaker.Permit) { p.OnSuccess(time.Millisecond) },
+		func(p circuitbreaker.Permit) { p.OnError(time.Millisecond, errDependency) },
+		func(p circuitbreaker.Permit) { p.OnResult("degraded", time.Millisecond) },
+	} {
+		permit, err := cb.TryAcquirePermission()
+		require.NoError(t, err)
+		record(permit)
+
+		// only the first call on a permit counts
+		permit.OnSuccess(time.Millisecond)
+	}
+
+	require.Equal(t, 3, cb.Metrics().BufferedCalls)
+
+	// a released permit is not recorded
+	permit, err := cb.TryAcquirePermission()
+	require.NoError(t, err)
+	permit.Release()
+	require.Equal(t, 3, cb.Metrics().BufferedCalls)
+
+	permit, err = cb.TryAcquirePermission()
+	require.NoError(t, err)
+	permit.OnError(time.Millisecond, errDependency)
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+
+	_, err = cb.TryAcquirePermission()
+	require.ErrorIs(t, err, circuitbreaker.ErrOpenState)
+}
+
+func TestCircuitBreaker_ReleasedPermitReturnsHalfOpenLease(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	cb := halfOpenBreaker(t, clock, circuitbreaker.WithPermittedNumberOfCallsInHalfOpenState(1))
+
+	permit, err := cb.TryAcquirePermission()
+	require.NoError(t, err)
+
+	_, err = cb.TryAcquirePermission()
+	require.ErrorIs(t, err, circuitbreaker.ErrHalfOpenState)
+
+	permit.Release()
+	permit.OnError(time.Millisecond, errDependency)
+	require.Equal(t, 1, cb.Metrics().HalfOpenPermitsAvailable)
+
+	permit, err = cb.TryAcquirePermission()
+	require.NoError(t, err)
+	permit.OnSuccess(time.Millisecond)
+	require.Equal(t, circuitbreaker.StateClosed, cb.State())
+}
+
+func TestCircuitBreaker_TryAcquirePermissionContext(t *testing.T) {
+	memory := circuitbreaker.NewInMemoryMetrics()
+	cb := circuitbreaker.New(
+		"permits",
+		circuitbreaker.WithMetrics(memory),
+		circuitbreaker.WithSlowCallDurationThreshold(time.Second),
+	)
+
+	// the threshold of the call context decides whether it is slow
+	ctx := circuitbreaker.ContextWithSlowCallDurationThreshold(context.Background(), 10*time.Millisecond)
+	permit, err := cb.TryAcquirePermissionContext(ctx)
+	require.NoError(t, err)
+	permit.OnSuccess(20 * time.Millisecond)
+
+	// a call failing after its caller cancelled is not blamed on the dependency
+	ctx, cancel := context.WithCancel(context.Background())
+	permit, err = cb.TryAcquirePermissionContext(ctx)
+	require.NoError(t, err)
+	cancel()
+	permit.OnError(time.Millisecond, errDependency)
+
+	stats, ok := memory.GetMetrics("permits")
+	require.True(t, ok)
+	require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeSlowSuccess])
+	require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeCancelled])
+	require.Equal(t, 1, cb.Metrics().BufferedCalls)
+}
@@ -0,0 +1,197 @@
+package circuitbreaker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var ErrAlreadyRegistered = errors.New("circuitbreaker: a circuit breaker with this name is already registered")
+
+// Registry holds circuit breakers by name so they can be shared and inspected,
+// e.g. by metrics collectors that read live state at collection time
+type Registry struct {
+	mu       sync.RWMutex
+	breakers map[string]CircuitBreaker
+
+	// defaults are applied before the options passed to GetOrCreate
+	defaults []Option
+
+	// pending holds loaded snapshots of circuit breakers that were not created yet
+	pending map[string]Snapshot
+}
+
+// NewRegistry creates a registry, opts are applied to every circuit breaker created through GetOrCreate
+func NewRegistry(opts ...Option) *Registry {
+	return &Registry{
+		breakers: make(map[string]CircuitBreaker),
+		defaults: opts,
+		pending:  make(map[string]Snapshot),
+	}
+}
+
+// GetOrCreate returns the circuit breaker registered under name, creating it with the
+// registry defaults followed by opts if it does not exist yet
+func (r *Registry) GetOrCreate(name string, opts ...Option) CircuitBreaker {
+	r.mu.RLock()
+	cb, ok := r.breakers[name]
+	r.mu.RUnlock()
+	if ok {
+		return cb
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cb, ok = r.breakers[name]; ok {
+		return cb
+	}
+
+	allOpts := make([]Option, 0, len(r.defaults)+len(opts))
+	allOpts = append(allOpts, r.defaults...)
+	allOpts = append(allOpts, opts...)
+
+	cb = New(name, allOpts...)
+	r.restorePendingUnsafe(cb)
+	r.breakers[name] = cb
+
+	return cb
+}
+
+// Register adds an existing circuit breaker to the registry
+func (r *Registry) Register(cb CircuitBreaker) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.breakers[cb.Name()]; ok {
+		return ErrAlreadyRegistered
+	}
+
+	r.restorePendingUnsafe(cb)
+	r.breakers[cb.Name()] = cb
+	return nil
+}
+
+// Get returns the circuit breaker registered under name
+func (r *Registry) Get(name string) (CircuitBreaker, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cb, ok := r.breakers[name]
+	return cb, ok
+}
+
+// Remove removes the circuit breaker registered under name, if any
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.breakers, name)
+}
+
+// All returns every registered circuit breaker sorted by name
+func (r *Registry) All() []CircuitBreaker {
+	r.mu.RLock()
+	all := make([]CircuitBreaker, 0, len(r.breakers))
+	for _, cb := range r.breakers {
+		all = append(all, cb)
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(all, func(a, b CircuitBreaker) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return all
+}
+
+func (r *Registry) restorePendingUnsafe(cb CircuitBreaker) {
+	snapshot, ok := r.pending[cb.Name()]
+	if !ok {
+		return
+	}
+
+	delete(r.pending, cb.Name())
+	_ = cb.Restore(snapshot)
+}
+
+type registrySnapshot struct {
+	Breakers []Snapshot
+}
+
+// Save writes a JSON snapshot of every registered circuit breaker to w
+func (r *Registry) Save(w io.Writer) error {
+	all := r.All()
+
+	snapshot := registrySnapshot{Breakers: make([]Snapshot, 0, len(all))}
+	for _, cb := range all {
+		snapshot.Breakers = append(snapshot.Breakers, cb.Snapshot())
+	}
+
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		return fmt.Errorf("circuitbreaker: failed to encode registry snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Load restores the circuit breakers from a JSON snapshot written by Save. Snapshots of
+// circuit breakers that are not registered yet are restored when they are created through
+// GetOrCreate or added through Register.
+func (r *Registry) Load(rd io.Reader) error {
+	var snapshot registrySnapshot
+	if err := json.NewDecoder(rd).Decode(&snapshot); err != nil {
+		return fmt.Errorf("circuitbreaker: failed to decode registry snapshot: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range snapshot.Breakers {
+		if cb, ok := r.breakers[s.Name]; ok {
+			if err := cb.Restore(s); err != nil {
+				return err
+			}
+			continue
+		}
+
+		r.pending[s.Name] = s
+	}
+
+	return nil
+}
+
+// SaveFile writes the snapshot to path, replacing the file atomically
+func (r *Registry) SaveFile(path string) error {
+	var buf bytes.Buffer
+	if err := r.Save(&buf); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("circuitbreaker: failed to write registry snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// LoadFile restores the snapshot from path, a missing file is not an error so the
+// first start of a process does not need special handling
+func (r *Registry) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("circuitbreaker: failed to read registry snapshot: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	return r.Load(f)
+}
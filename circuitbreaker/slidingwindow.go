@@ -1,5 +1,7 @@
 package circuitbreaker
 
+import "sync"
+
 type CallOutcome int
 
 const (
@@ -7,15 +9,151 @@ const (
 	OutcomeFailure
 	OutcomeSlowSuccess
 	OutcomeSlowFailure
+
+	// OutcomeIgnored is a call whose error matched IgnoreErrors, it is not recorded in the window
+	// and does not count towards any rate
+	OutcomeIgnored
+
+	// OutcomeTimeout is a failure caused by timeout.ErrTimeout or context.DeadlineExceeded
+	OutcomeTimeout
+
+	// OutcomeCancelled is a call that failed after its caller cancelled it, it is not recorded in
+	// the window because the failure says nothing about the dependency
+	OutcomeCancelled
 )
 
+func (o CallOutcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeFailure:
+		return "failure"
+	case OutcomeSlowSuccess:
+		return "slow_success"
+	case OutcomeSlowFailure:
+		return "slow_failure"
+	case OutcomeIgnored:
+		return "ignored"
+	case OutcomeTimeout:
+		return "timeout"
+	case OutcomeCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// IsFailure reports whether the outcome is a failure, slow or not, including timeouts
+func (o CallOutcome) IsFailure() bool {
+	return o == OutcomeFailure || o == OutcomeSlowFailure || o == OutcomeTimeout
+}
+
+// IsIgnored reports whether the outcome is left out of the window
+func (o CallOutcome) IsIgnored() bool {
+	return o == OutcomeIgnored || o == OutcomeCancelled
+}
+
+// IsSlow reports whether the outcome is a slow call, successful or not
+func (o CallOutcome) IsSlow() bool {
+	return o == OutcomeSlowSuccess || o == OutcomeSlowFailure
+}
+
+// FailureCategory groups failures so each kind can be rated and thresholded on its own
+type FailureCategory string
+
+const (
+	// FailureCategoryError is any failure that is not a timeout
+	FailureCategoryError FailureCategory = "error"
+
+	// FailureCategoryTimeout is a failure caused by timeout.ErrTimeout or context.DeadlineExceeded
+	FailureCategoryTimeout FailureCategory = "timeout"
+)
+
+// CallRecord is a classified call as recorded in a window
+type CallRecord struct {
+	Outcome CallOutcome
+
+	// Category is only set for failures
+	Category FailureCategory `json:",omitempty"`
+
+	// Weight is how much the call counts towards the rates, 1 unless an ErrorWeight matched
+	Weight float64
+}
+
 type Window interface {
+	// Size is the number of calls recorded in the window
 	Size() int
 
-	RecordOutcome(CallOutcome)
+	// Record adds a call to the window, records of ignored and cancelled calls are dropped
+	Record(CallRecord)
 
-	// CallRates returns the total calls, success rate, failure rate, and slow call rate in percentage.
-	CallRates() (int, float64, float64, float64)
+	// CallRates returns the total calls and the weighted rates, see CallRates, the Name and
+	// SlowCallDurationThreshold of the returned rates are set by the circuit breaker
+	CallRates() CallRates
+
+	// Records returns the recorded calls from oldest to newest,
+	// recording them again into an empty window restores it
+	Records() []CallRecord
 
 	Reset()
 }
+
+// concurrentWindow is implemented by windows that are safe for concurrent use
+type concurrentWindow interface {
+	Window
+	concurrent()
+}
+
+// clockedWindow is implemented by windows that read the time, they use the Clock of the circuit breaker
+type clockedWindow interface {
+	setClock(Clock)
+}
+
+// newConcurrentWindow prepares window for a circuit breaker, one that is not safe for concurrent
+// use is guarded by a lock of its own so calls are recorded without holding the circuit breaker's
+func newConcurrentWindow(window Window, clock Clock) Window {
+	if w, ok := window.(clockedWindow); ok {
+		w.setClock(clock)
+	}
+
+	if _, ok := window.(concurrentWindow); ok {
+		return window
+	}
+
+	return &lockedWindow{window: window}
+}
+
+type lockedWindow struct {
+	mu     sync.Mutex
+	window Window
+}
+
+func (w *lockedWindow) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.window.Size()
+}
+
+func (w *lockedWindow) Record(record CallRecord) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.window.Record(record)
+}
+
+func (w *lockedWindow) CallRates() CallRates {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.window.CallRates()
+}
+
+func (w *lockedWindow) Records() []CallRecord {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.window.Records()
+}
+
+func (w *lockedWindow) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.window.Reset()
+}
@@ -1,86 +1,151 @@
 package circuitbreaker
 
 import (
-	"container/ring"
+	"maps"
 )
 
 var _ Window = (*CountWindow)(nil)
 
+// CountWindow keeps the last size calls. It is not safe for concurrent use, the circuit breaker
+// guards it with a mutex of the window's own.
 type CountWindow struct {
-	ring *ring.Ring
+	records []CallRecord
 
-	successCount  int32
-	failureCount  int32
-	slowCallCount int32
+	// next is the position overwritten by the next record, once the window is full it holds the oldest record
+	next int
+	full bool
+
+	sums windowSums
 }
 
 func NewCountWindow(size int) *CountWindow {
 	return &CountWindow{
-		ring: ring.New(size),
+		records: make([]CallRecord, max(1, size)),
 	}
 }
 
-func (w *CountWindow) RecordOutcome(outcome CallOutcome) {
-	oldOutcome, ok := w.ring.Value.(CallOutcome)
-	if ok {
-		w.decrementOutcome(oldOutcome)
+func (w *CountWindow) Record(record CallRecord) {
+	if record.Outcome.IsIgnored() {
+		return
+	}
+
+	if w.full {
+		w.sums.add(w.records[w.next], -1)
+	}
+
+	w.records[w.next] = record
+	w.sums.add(record, 1)
+
+	w.next++
+	if w.next == len(w.records) {
+		w.next = 0
+		w.full = true
 	}
+}
+
+func (w *CountWindow) Size() int {
+	return w.sums.calls
+}
 
-	w.ring.Value = outcome
-	w.incrementOutcome(outcome)
-	w.ring = w.ring.Next()
+func (w *CountWindow) Reset() {
+	clear(w.records)
+	w.next = 0
+	w.full = false
+	w.sums.reset()
 }
 
-func (w *CountWindow) decrementOutcome(outcome CallOutcome) {
-	switch outcome {
-	case OutcomeSuccess:
-		w.successCount--
-	case OutcomeFailure:
-		w.failureCount--
-	case OutcomeSlowSuccess:
-		w.successCount--
-		w.slowCallCount--
-	case OutcomeSlowFailure:
-		w.failureCount--
-		w.slowCallCount--
+func (w *CountWindow) Records() []CallRecord {
+	records := make([]CallRecord, 0, w.Size())
+	if w.full {
+		records = append(records, w.records[w.next:]...)
 	}
+
+	return append(records, w.records[:w.next]...)
+}
+
+func (w *CountWindow) CallRates() CallRates {
+	return w.sums.rates()
+}
+
+// windowSums are the totals of the records in a window, the weights are summed
+// so the rates reflect how much each call counts
+type windowSums struct {
+	calls          int
+	totalWeight    float64
+	failureWeight  float64
+	slowCallWeight float64
+	categoryWeight map[FailureCategory]float64
 }
 
-func (w *CountWindow) incrementOutcome(outcome CallOutcome) {
-	switch outcome {
-	case OutcomeSuccess:
-		w.successCount++
-	case OutcomeFailure:
-		w.failureCount++
-	case OutcomeSlowSuccess:
-		w.successCount++
-		w.slowCallCount++
-	case OutcomeSlowFailure:
-		w.failureCount++
-		w.slowCallCount++
+func (s *windowSums) add(record CallRecord, sign int) {
+	weight := float64(sign) * record.Weight
+
+	s.calls += sign
+	s.totalWeight += weight
+
+	if record.Outcome.IsSlow() {
+		s.slowCallWeight += weight
+	}
+
+	if record.Outcome.IsFailure() {
+		s.failureWeight += weight
+		s.addCategory(record.Category, weight)
+		if s.categoryWeight[record.Category] <= 0 {
+			delete(s.categoryWeight, record.Category)
+		}
+	}
+
+	if s.calls == 0 {
+		// drop the rounding errors left by adding and subtracting weights
+		s.reset()
 	}
 }
 
-func (w *CountWindow) Size() int {
-	return int(w.successCount + w.failureCount)
+// merge adds the totals of other, used to aggregate the shards of a ShardedWindow
+func (s *windowSums) merge(other *windowSums) {
+	s.calls += other.calls
+	s.totalWeight += other.totalWeight
+	s.failureWeight += other.failureWeight
+	s.slowCallWeight += other.slowCallWeight
+
+	for category, weight := range other.categoryWeight {
+		s.addCategory(category, weight)
+	}
 }
 
-func (w *CountWindow) Reset() {
-	w.ring = ring.New(w.ring.Len())
-	w.successCount = 0
-	w.failureCount = 0
-	w.slowCallCount = 0
+// addCategory allocates the category weights lazily, so windows without failures never do
+func (s *windowSums) addCategory(category FailureCategory, weight float64) {
+	if s.categoryWeight == nil {
+		s.categoryWeight = make(map[FailureCategory]float64)
+	}
+	s.categoryWeight[category] += weight
 }
 
-func (w *CountWindow) CallRates() (int, float64, float64, float64) {
-	totalCalls := w.Size()
-	if totalCalls == 0 {
-		return 0, 0, 0, 0
+func (s *windowSums) reset() {
+	s.calls = 0
+	s.totalWeight = 0
+	s.failureWeight = 0
+	s.slowCallWeight = 0
+	clear(s.categoryWeight)
+}
+
+func (s *windowSums) rates() CallRates {
+	rates := CallRates{TotalCalls: s.calls}
+	if s.calls == 0 || s.totalWeight <= 0 {
+		return rates
 	}
 
-	successRate := float64(w.successCount) / float64(totalCalls)
-	failureRate := float64(w.failureCount) / float64(totalCalls)
-	slowCallRate := float64(w.slowCallCount) / float64(totalCalls)
+	rates.SuccessRate = (s.totalWeight - s.failureWeight) / s.totalWeight
+	rates.FailureRate = s.failureWeight / s.totalWeight
+	rates.SlowCallRate = s.slowCallWeight / s.totalWeight
+	rates.setPercents()
+
+	if len(s.categoryWeight) > 0 {
+		rates.CategoryRatesPercent = maps.Clone(s.categoryWeight)
+		for category, weight := range rates.CategoryRatesPercent {
+			rates.CategoryRatesPercent[category] = weight * 100 / s.totalWeight
+		}
+	}
 
-	return totalCalls, successRate, failureRate, slowCallRate
+	return rates
 }
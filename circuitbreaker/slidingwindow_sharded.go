@@ -0,0 +1,183 @@
+package circuitbreaker
+
+import (
+	"maps"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ Window = (*ShardedWindow)(nil)
+
+// cacheLineSize pads the shards so concurrent updates to neighbouring shards do not share a cache line
+const cacheLineSize = 64
+
+type shardedWindowConfig struct {
+	shards              int
+	aggregationInterval time.Duration
+}
+
+type ShardedWindowOption func(*shardedWindowConfig)
+
+// WithWindowShards sets the number of shards, defaults to GOMAXPROCS
+func WithWindowShards(n int) ShardedWindowOption {
+	return func(c *shardedWindowConfig) {
+		c.shards = n
+	}
+}
+
+// WithWindowAggregationInterval reuses the aggregated rates for interval instead of locking every
+// shard on each read, so the rates may lag behind the recorded calls by up to interval
+func WithWindowAggregationInterval(interval time.Duration) ShardedWindowOption {
+	return func(c *shardedWindowConfig) {
+		c.aggregationInterval = interval
+	}
+}
+
+type windowShard struct {
+	mu     sync.Mutex
+	window *CountWindow
+
+	_ [cacheLineSize]byte
+}
+
+type aggregatedRates struct {
+	rates CallRates
+	at    time.Time
+
+	// generation is the Reset count the rates were aggregated after
+	generation uint64
+}
+
+// ShardedWindow is a Window for circuit breakers with high call rates. Calls are recorded into
+// one of several shards picked at random, each a CountWindow with its own lock, so concurrent
+// calls rarely contend on the same lock. Reads aggregate the shards.
+//
+// The size is split between the shards, so the window holds at most size calls, but not
+// necessarily the last size calls as calls are spread unevenly. Records returns the calls in
+// order per shard only.
+// Unlike CountWindow it is safe for concurrent use.
+//
+// The circuit breaker records calls and reads the rates without holding its own lock, it only
+// holds it to evaluate its state. With WithWindowAggregationInterval reading the rates is mostly
+// an atomic load instead of locking every shard, the interval is measured with the Clock of the
+// circuit breaker.
+type ShardedWindow struct {
+	shards              []windowShard
+	aggregationInterval time.Duration
+	clock               Clock
+
+	aggregated atomic.Pointer[aggregatedRates]
+
+	// generation counts the resets, so rates aggregated before a reset are not cached after it
+	generation atomic.Uint64
+}
+
+func NewShardedWindow(size int, opts ...ShardedWindowOption) *ShardedWindow {
+	config := shardedWindowConfig{shards: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	shards := max(1, min(config.shards, size))
+
+	w := &ShardedWindow{
+		shards:              make([]windowShard, shards),
+		aggregationInterval: config.aggregationInterval,
+		clock:               SystemClock(),
+	}
+	// the first shards take the remainder, so the shard sizes add up to size
+	for i := range w.shards {
+		shardSize := size / shards
+		if i < size%shards {
+			shardSize++
+		}
+		w.shards[i].window = NewCountWindow(max(1, shardSize))
+	}
+
+	return w
+}
+
+func (w *ShardedWindow) concurrent() {}
+
+func (w *ShardedWindow) setClock(clock Clock) {
+	w.clock = clock
+}
+
+func (w *ShardedWindow) Record(record CallRecord) {
+	if record.Outcome.IsIgnored() {
+		return
+	}
+
+	shard := &w.shards[rand.IntN(len(w.shards))]
+
+	shard.mu.Lock()
+	shard.window.Record(record)
+	shard.mu.Unlock()
+}
+
+func (w *ShardedWindow) Size() int {
+	return w.CallRates().TotalCalls
+}
+
+func (w *ShardedWindow) CallRates() CallRates {
+	generation := w.generation.Load()
+	if w.aggregationInterval > 0 {
+		if cached := w.aggregated.Load(); cached != nil && cached.generation == generation &&
+			w.clock.Now().Sub(cached.at) < w.aggregationInterval {
+			return cloneRates(cached.rates)
+		}
+	}
+
+	var sums windowSums
+	for i := range w.shards {
+		shard := &w.shards[i]
+
+		shard.mu.Lock()
+		sums.merge(&shard.window.sums)
+		shard.mu.Unlock()
+	}
+
+	rates := sums.rates()
+	// a concurrent Reset may have cleared some shards after they were merged
+	if w.aggregationInterval > 0 && w.generation.Load() == generation {
+		w.aggregated.Store(&aggregatedRates{rates: cloneRates(rates), at: w.clock.Now(), generation: generation})
+	}
+
+	return rates
+}
+
+func (w *ShardedWindow) Records() []CallRecord {
+	var records []CallRecord
+	for i := range w.shards {
+		shard := &w.shards[i]
+
+		shard.mu.Lock()
+		records = append(records, shard.window.Records()...)
+		shard.mu.Unlock()
+	}
+
+	return records
+}
+
+func (w *ShardedWindow) Reset() {
+	for i := range w.shards {
+		shard := &w.shards[i]
+
+		shard.mu.Lock()
+		shard.window.Reset()
+		shard.mu.Unlock()
+	}
+
+	// bumped once every shard is cleared, rates merged while clearing are never used after it
+	w.generation.Add(1)
+	w.aggregated.Store(nil)
+}
+
+// cloneRates copies the category rates, so cached rates are not shared with callers
+func cloneRates(rates CallRates) CallRates {
+	rates.CategoryRatesPercent = maps.Clone(rates.CategoryRatesPercent)
+	return rates
+}
@@ -0,0 +1,231 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+var (
+	successRecord = circuitbreaker.CallRecord{Outcome: circuitbreaker.OutcomeSuccess, Weight: 1}
+	failureRecord = circuitbreaker.CallRecord{
+		Outcome: circuitbreaker.OutcomeFailure, Category: circuitbreaker.FailureCategoryError, Weight: 1,
+	}
+)
+
+func TestShardedWindow_ConcurrentRecords(t *testing.T) {
+	w := circuitbreaker.NewShardedWindow(4000, circuitbreaker.WithWindowShards(4))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for i := range 100 {
+				if i%4 == 0 {
+					w.Record(failureRecord)
+				} else {
+					w.Record(successRecord)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	rates := w.CallRates()
+	require.Equal(t, 800, rates.TotalCalls)
+	require.Equal(t, 800, w.Size())
+	require.Len(t, w.Records(), 800)
+	require.InDelta(t, 0.25, rates.FailureRate, 1e-9)
+	require.InDelta(t, 25, rates.CategoryRatesPercent[circuitbreaker.FailureCategoryError], 1e-9)
+
+	w.Reset()
+	require.Zero(t, w.Size())
+}
+
+func TestShardedWindow_HoldsAtMostSize(t *testing.T) {
+	w := circuitbreaker.NewShardedWindow(10, circuitbreaker.WithWindowShards(4))
+
+	for range 1000 {
+		w.Record(successRecord)
+	}
+
+	// the shards hold 3, 3, 2 and 2 calls
+	require.Equal(t, 10, w.CallRates().TotalCalls)
+	require.Len(t, w.Records(), 10)
+}
+
+func TestShardedWindow_AggregationInterval(t *testing.T) {
+	w := circuitbreaker.NewShardedWindow(
+		100,
+		circuitbreaker.WithWindowShards(2),
+		circuitbreaker.WithWindowAggregationInterval(time.Hour),
+	)
+
+	w.Record(failureRecord)
+	require.Equal(t, 1, w.CallRates().TotalCalls)
+
+	// the aggregated rates are reused until the interval elapsed
+	w.Record(successRecord)
+	require.Equal(t, 1, w.CallRates().TotalCalls)
+	require.InDelta(t, 1, w.CallRates().FailureRate, 1e-9)
+
+	w.Reset()
+	require.Zero(t, w.CallRates().TotalCalls)
+}
+
+func TestCircuitBreaker_ShardedWindowUsesClock(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	cb := circuitbreaker.New(
+		"sharded",
+		circuitbreaker.WithClock(clock),
+		circuitbreaker.WithMetrics(&circuitbreaker.NoopMetrics{}),
+		circuitbreaker.WithWindow(
+			circuitbreaker.NewShardedWindow(
+				100, circuitbreaker.WithWindowShards(2), circuitbreaker.WithWindowAggregationInterval(time.Second),
+			),
+		),
+	)
+
+	require.NoError(t, circuitbreaker.Do(t.Context(), cb, succeedingCall))
+	require.NoError(t, circuitbreaker.Do(t.Context(), cb, succeedingCall))
+	require.Equal(t, 1, cb.Metrics().BufferedCalls)
+
+	// the aggregation interval elapses on the clock of the circuit breaker
+	clock.Advance(time.Second)
+	require.Equal(t, 2, cb.Metrics().BufferedCalls)
+}
+
+func TestCircuitBreaker_ShardedWindow(t *testing.T) {
+	cb := circuitbreaker.New(
+		"sharded",
+		circuitbreaker.WithMetrics(&circuitbreaker.NoopMetrics{}),
+		circuitbreaker.WithWindow(circuitbreaker.NewShardedWindow(100, circuitbreaker.WithWindowShards(4))),
+		circuitbreaker.WithMinimumNumberOfCalls(10),
+	)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			_ = circuitbreaker.Do(t.Context(), cb, failingCall)
+		})
+	}
+	wg.Wait()
+
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+}
+
+// BenchmarkWindow_Parallel records a call and reads the rates, as the circuit breaker does after
+// every call, with the CountWindow guarded by a mutex the way the circuit breaker guards it
+func BenchmarkWindow_Parallel(b *testing.B) {
+	b.Run(
+		"CountWindow", func(b *testing.B) {
+			var mu sync.Mutex
+			w := circuitbreaker.NewCountWindow(1000)
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					mu.Lock()
+					w.Record(successRecord)
+					_ = w.CallRates()
+					mu.Unlock()
+				}
+			})
+		},
+	)
+
+	b.Run(
+		"ShardedWindow", func(b *testing.B) {
+			w := circuitbreaker.NewShardedWindow(1000)
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					w.Record(successRecord)
+					_ = w.CallRates()
+				}
+			})
+		},
+	)
+
+	b.Run(
+		"ShardedWindowAggregated", func(b *testing.B) {
+			w := circuitbreaker.NewShardedWindow(1000, circuitbreaker.WithWindowAggregationInterval(10*time.Millisecond))
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					w.Record(successRecord)
+					_ = w.CallRates()
+				}
+			})
+		},
+	)
+}
+
+func BenchmarkWindow_ParallelRecord(b *testing.B) {
+	b.Run(
+		"CountWindow", func(b *testing.B) {
+			var mu sync.Mutex
+			w := circuitbreaker.NewCountWindow(1000)
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					mu.Lock()
+					w.Record(successRecord)
+					mu.Unlock()
+				}
+			})
+		},
+	)
+
+	b.Run(
+		"ShardedWindow", func(b *testing.B) {
+			w := circuitbreaker.NewShardedWindow(1000)
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					w.Record(successRecord)
+				}
+			})
+		},
+	)
+}
+
+// BenchmarkCircuitBreaker_ParallelExecute runs calls through Execute, the circuit breaker records
+// them and reads the rates without holding its own lock
+func BenchmarkCircuitBreaker_ParallelExecute(b *testing.B) {
+	windows := []struct {
+		name   string
+		window func() circuitbreaker.Window
+	}{
+		{name: "CountWindow", window: func() circuitbreaker.Window { return circuitbreaker.NewCountWindow(1000) }},
+		{name: "ShardedWindow", window: func() circuitbreaker.Window { return circuitbreaker.NewShardedWindow(1000) }},
+		{
+			name: "ShardedWindowAggregated",
+			window: func() circuitbreaker.Window {
+				return circuitbreaker.NewShardedWindow(1000, circuitbreaker.WithWindowAggregationInterval(10*time.Millisecond))
+			},
+		},
+	}
+
+	for _, tc := range windows {
+		b.Run(
+			tc.name, func(b *testing.B) {
+				cb := circuitbreaker.New(
+					"bench",
+					circuitbreaker.WithMetrics(&circuitbreaker.NoopMetrics{}),
+					circuitbreaker.WithWindow(tc.window()),
+				)
+				b.Cleanup(func() { _ = cb.Close() })
+
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						_ = circuitbreaker.Do(context.Background(), cb, succeedingCall)
+					}
+				})
+			},
+		)
+	}
+}
@@ -0,0 +1,149 @@
+package circuitbreaker
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type slowCallDurationThresholdKey struct{}
+
+// ContextWithSlowCallDurationThreshold overrides the slow call duration threshold for calls made
+// with ctx through Execute, Do or a permit of TryAcquirePermissionContext, e.g. for requests that
+// are known to take longer than most
+func ContextWithSlowCallDurationThreshold(ctx context.Context, threshold time.Duration) context.Context {
+	return context.WithValue(ctx, slowCallDurationThresholdKey{}, threshold)
+}
+
+// SlowCallDurationThresholdFromContext returns the threshold set with ContextWithSlowCallDurationThreshold
+func SlowCallDurationThresholdFromContext(ctx context.Context) (time.Duration, bool) {
+	threshold, ok := ctx.Value(slowCallDurationThresholdKey{}).(time.Duration)
+	return threshold, ok && threshold > 0
+}
+
+// AdaptiveSlowCallThreshold defines slow calls relative to the recent latency of the circuit
+// breaker instead of a fixed duration: a call is slow when it takes longer than Multiplier times
+// the Percentile of the durations of the calls recorded over the last Window.
+type AdaptiveSlowCallThreshold struct {
+	// Percentile is between 0 and 100, e.g. 50 for the median
+	Percentile float64
+	Multiplier float64
+	Window     time.Duration
+}
+
+const (
+	// latencySlots is the number of time slots a latency window is split into, the
+	// threshold is derived again each time a slot elapsed
+	latencySlots = 10
+
+	// latency buckets grow by latencyBucketGrowth from latencyBucketMin, so a percentile is
+	// accurate to within 10% and the last bucket holds calls of about an hour or more
+	latencyBucketMin    = time.Microsecond
+	latencyBucketGrowth = 1.1
+	latencyBuckets      = 232
+)
+
+type latencySlot struct {
+	counts [latencyBuckets]uint32
+	total  uint64
+}
+
+// latencyTracker keeps a histogram of call durations per time slot, so percentiles
+// are computed over a rolling window at a fixed cost per call
+type latencyTracker struct {
+	config AdaptiveSlowCallThreshold
+	clock  Clock
+
+	mu          sync.Mutex
+	slots       [latencySlots]latencySlot
+	current     int
+	currentFrom time.Time
+	derived     time.Duration
+}
+
+func newLatencyTracker(config AdaptiveSlowCallThreshold, clock Clock) *latencyTracker {
+	return &latencyTracker{
+		config:      config,
+		clock:       clock,
+		currentFrom: clock.Now(),
+	}
+}
+
+func (t *latencyTracker) record(duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.advanceUnsafe()
+
+	slot := &t.slots[t.current]
+	slot.counts[latencyBucket(duration)]++
+	slot.total++
+}
+
+// threshold returns the derived threshold, zero until the first slot elapsed or while no calls are recorded
+func (t *latencyTracker) threshold() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.advanceUnsafe()
+	return t.derived
+}
+
+func (t *latencyTracker) advanceUnsafe() {
+	slotDuration := max(t.config.Window/latencySlots, time.Millisecond)
+
+	elapsed := t.clock.Now().Sub(t.currentFrom)
+	if elapsed < slotDuration {
+		return
+	}
+
+	steps := int64(elapsed / slotDuration)
+	for range min(steps, latencySlots) {
+		t.current = (t.current + 1) % latencySlots
+		t.slots[t.current] = latencySlot{}
+	}
+	t.currentFrom = t.currentFrom.Add(time.Duration(steps) * slotDuration)
+
+	t.derived = time.Duration(float64(t.percentileUnsafe()) * t.config.Multiplier)
+}
+
+func (t *latencyTracker) percentileUnsafe() time.Duration {
+	var total uint64
+	for i := range t.slots {
+		total += t.slots[i].total
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(float64(total) * min(max(t.config.Percentile, 0), 100) / 100))
+	rank = max(rank, 1)
+
+	var seen uint64
+	for bucket := range latencyBuckets {
+		for i := range t.slots {
+			seen += uint64(t.slots[i].counts[bucket])
+		}
+
+		if seen >= rank {
+			return latencyBucketBound(bucket)
+		}
+	}
+
+	return latencyBucketBound(latencyBuckets - 1)
+}
+
+func latencyBucket(duration time.Duration) int {
+	if duration <= latencyBucketMin {
+		return 0
+	}
+
+	bucket := int(math.Ceil(math.Log(float64(duration)/float64(latencyBucketMin)) / math.Log(latencyBucketGrowth)))
+	return min(bucket, latencyBuckets-1)
+}
+
+// latencyBucketBound is the upper bound of a bucket, percentiles round up to it
+func latencyBucketBound(bucket int) time.Duration {
+	return time.Duration(float64(latencyBucketMin) * math.Pow(latencyBucketGrowth, float64(bucket)))
+}
@@ -0,0 +1,85 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+func TestCircuitBreaker_SlowCallDurationThresholdFromContext(t *testing.T) {
+	memory := circuitbreaker.NewInMemoryMetrics()
+	cb := circuitbreaker.New(
+		"per-call",
+		circuitbreaker.WithMetrics(memory),
+		circuitbreaker.WithSlowCallDurationThreshold(time.Hour),
+	)
+
+	sleepingCall := func(context.Context) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+
+	require.NoError(t, circuitbreaker.Do(context.Background(), cb, sleepingCall))
+
+	ctx := circuitbreaker.ContextWithSlowCallDurationThreshold(context.Background(), time.Microsecond)
+	require.NoError(t, circuitbreaker.Do(ctx, cb, sleepingCall))
+
+	stats, ok := memory.GetMetrics("per-call")
+	require.True(t, ok)
+	require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeSuccess])
+	require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeSlowSuccess])
+
+	// the threshold of the call does not change the one reported for the circuit breaker
+	require.Equal(t, time.Hour, stats.LastRates.SlowCallDurationThreshold)
+}
+
+func TestCircuitBreaker_AdaptiveSlowCallThreshold(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	memory := circuitbreaker.NewInMemoryMetrics()
+	cb := circuitbreaker.New(
+		"adaptive",
+		circuitbreaker.WithClock(clock),
+		circuitbreaker.WithMetrics(memory),
+		circuitbreaker.WithSlowCallDurationThreshold(time.Second),
+		circuitbreaker.WithAdaptiveSlowCallThreshold(50, 3, 10*time.Second),
+	)
+
+	record := func(duration time.Duration) circuitbreaker.BreakerStats {
+		t.Helper()
+
+		permit, err := cb.TryAcquirePermission()
+		require.NoError(t, err)
+		permit.OnSuccess(duration)
+
+		stats, ok := memory.GetMetrics("adaptive")
+		require.True(t, ok)
+		return stats
+	}
+
+	// the static threshold applies until the first slot of the latency window elapsed
+	for range 9 {
+		record(10 * time.Millisecond)
+	}
+	stats := record(500 * time.Millisecond)
+	require.Equal(t, time.Second, stats.LastRates.SlowCallDurationThreshold)
+	require.Zero(t, stats.Calls[circuitbreaker.OutcomeSlowSuccess])
+
+	clock.Advance(time.Second)
+
+	stats = record(20 * time.Millisecond)
+	require.Zero(t, stats.Calls[circuitbreaker.OutcomeSlowSuccess])
+	require.InDelta(t, 30*time.Millisecond, stats.LastRates.SlowCallDurationThreshold, float64(3*time.Millisecond))
+
+	stats = record(40 * time.Millisecond)
+	require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeSlowSuccess])
+
+	// once every recorded call aged out of the latency window the static threshold applies again
+	clock.Advance(time.Minute)
+	stats = record(500 * time.Millisecond)
+	require.Equal(t, time.Second, stats.LastRates.SlowCallDurationThreshold)
+	require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeSlowSuccess])
+}
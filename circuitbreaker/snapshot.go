@@ -0,0 +1,68 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrSnapshotNameMismatch = errors.New("circuitbreaker: snapshot belongs to a different circuit breaker")
+
+// Snapshot is the persistable state of a circuit breaker. Restoring it keeps the original
+// transition time, so an open circuit breaker only waits for the remainder of its wait duration.
+type Snapshot struct {
+	Name           string
+	State          State
+	TransitionTime time.Time
+	Records        []CallRecord
+
+	// OpenAttempts and WaitDurationInOpenState keep the open state backoff across restarts
+	OpenAttempts            uint
+	WaitDurationInOpenState time.Duration
+}
+
+func (cb *circuitBreakerImpl) Snapshot() Snapshot {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	return Snapshot{
+		Name:           cb.name,
+		State:          cb.state,
+		TransitionTime: cb.transitionTime,
+		Records:        cb.window.Records(),
+
+		OpenAttempts:            cb.openAttempts,
+		WaitDurationInOpenState: cb.openWait,
+	}
+}
+
+// Restore replaces the state and window contents with the snapshot. The state of a circuit
+// breaker in metrics only mode is kept and only its window is restored, as is the state of any
+// circuit breaker when the snapshot was taken in metrics only mode.
+func (cb *circuitBreakerImpl) Restore(snapshot Snapshot) error {
+	if snapshot.Name != cb.name {
+		return fmt.Errorf("%w: got %q, want %q", ErrSnapshotNameMismatch, snapshot.Name, cb.name)
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != StateMetricsOnly && snapshot.State != StateMetricsOnly {
+		cb.setStateUnsafe(snapshot.State)
+		cb.transitionTime = snapshot.TransitionTime
+
+		if snapshot.OpenAttempts > 0 {
+			cb.openAttempts = snapshot.OpenAttempts
+			cb.openWait = snapshot.WaitDurationInOpenState
+		}
+
+		cb.scheduleStateTimerUnsafe()
+	}
+
+	cb.window.Reset()
+	for _, record := range snapshot.Records {
+		cb.window.Record(record)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+func TestCountWindow_Records(t *testing.T) {
+	w := circuitbreaker.NewCountWindow(3)
+	require.Empty(t, w.Records())
+
+	for _, outcome := range []circuitbreaker.CallOutcome{
+		circuitbreaker.OutcomeSuccess,
+		circuitbreaker.OutcomeFailure,
+		circuitbreaker.OutcomeSlowSuccess,
+		circuitbreaker.OutcomeSlowFailure,
+	} {
+		w.Record(circuitbreaker.CallRecord{Outcome: outcome, Weight: 1})
+	}
+
+	require.Equal(
+		t, []circuitbreaker.CallRecord{
+			{Outcome: circuitbreaker.OutcomeFailure, Weight: 1},
+			{Outcome: circuitbreaker.OutcomeSlowSuccess, Weight: 1},
+			{Outcome: circuitbreaker.OutcomeSlowFailure, Weight: 1},
+		}, w.Records(),
+	)
+}
+
+func TestCircuitBreaker_RestoreResumesOpenState(t *testing.T) {
+	opts := []circuitbreaker.Option{
+		circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)),
+		circuitbreaker.WithWaitDurationInOpenState(time.Minute),
+	}
+
+	cb := circuitbreaker.New("test", opts...)
+	require.NoError(
+		t, cb.Restore(
+			circuitbreaker.Snapshot{
+				Name:           "test",
+				State:          circuitbreaker.StateOpen,
+				TransitionTime: time.Now().Add(-50 * time.Second),
+			},
+		),
+	)
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+	require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, succeedingCall), circuitbreaker.ErrOpenState)
+
+	// the wait duration had already passed before the restart
+	cb = circuitbreaker.New("test", opts...)
+	require.NoError(
+		t, cb.Restore(
+			circuitbreaker.Snapshot{
+				Name:           "test",
+				State:          circuitbreaker.StateOpen,
+				TransitionTime: time.Now().Add(-2 * time.Minute),
+			},
+		),
+	)
+	require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+	require.Equal(t, circuitbreaker.StateHalfOpen, cb.State())
+
+	err := cb.Restore(circuitbreaker.Snapshot{Name: "other"})
+	require.ErrorIs(t, err, circuitbreaker.ErrSnapshotNameMismatch)
+}
+
+func TestCircuitBreaker_SnapshotRoundTrip(t *testing.T) {
+	cb := circuitbreaker.New(
+		"test",
+		circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)),
+		circuitbreaker.WithMinimumNumberOfCalls(5),
+	)
+
+	require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+	require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, failingCall), errDependency)
+
+	snapshot := cb.Snapshot()
+	require.Equal(t, circuitbreaker.StateClosed, snapshot.State)
+	require.Equal(
+		t, []circuitbreaker.CallRecord{
+			{Outcome: circuitbreaker.OutcomeSuccess, Weight: 1},
+			{Outcome: circuitbreaker.OutcomeFailure, Category: circuitbreaker.FailureCategoryError, Weight: 1},
+		}, snapshot.Records,
+	)
+
+	restored := circuitbreaker.New("test", circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)))
+	require.NoError(t, restored.Restore(snapshot))
+	require.Equal(t, snapshot.Records, restored.Snapshot().Records)
+	require.Equal(t, 2, restored.Metrics().BufferedCalls)
+	require.InDelta(t, 0.5, restored.Metrics().FailureRate, 1e-9)
+}
+
+func TestRegistry_SaveAndLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breakers.json")
+
+	before := circuitbreaker.NewRegistry(
+		circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)),
+		circuitbreaker.WithMinimumNumberOfCalls(2),
+		circuitbreaker.WithFailureRateThreshold(50),
+	)
+	down := before.GetOrCreate("down")
+	up := before.GetOrCreate("up")
+
+	for i := 0; i < 2; i++ {
+		_ = circuitbreaker.Do(context.Background(), down, failingCall)
+	}
+	require.NoError(t, circuitbreaker.Do(context.Background(), up, succeedingCall))
+	require.Equal(t, circuitbreaker.StateOpen, down.State())
+
+	require.NoError(t, before.SaveFile(path))
+
+	after := circuitbreaker.NewRegistry(circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)))
+
+	// up is registered before loading, down only afterwards
+	up = after.GetOrCreate("up")
+	require.NoError(t, after.LoadFile(path))
+	require.Equal(t, 1, up.Metrics().BufferedCalls)
+
+	down = after.GetOrCreate("down")
+	require.Equal(t, circuitbreaker.StateOpen, down.State())
+	require.Equal(t, before.All()[0].Metrics().TransitionTime.UnixNano(), down.Metrics().TransitionTime.UnixNano())
+	require.ErrorIs(t, circuitbreaker.Do(context.Background(), down, succeedingCall), circuitbreaker.ErrOpenState)
+
+	// the first start of a process has nothing to load
+	require.NoError(t, circuitbreaker.NewRegistry().LoadFile(filepath.Join(t.TempDir(), "missing.json")))
+}
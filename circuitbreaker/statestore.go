@@ -0,0 +1,230 @@
+package circuitbreaker
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// SharedState is the circuit breaker state shared between instances through a StateStore
+type SharedState struct {
+	State          State
+	TransitionTime time.Time
+
+	// Version is incremented by every successful CompareAndSwapState, zero means no state is stored
+	Version uint64
+
+	// UpdatedBy is the instance that stored this version
+	UpdatedBy string
+}
+
+// WindowCounts are the calls recorded in an instance's window
+type WindowCounts struct {
+	Calls     int64
+	Failures  int64
+	SlowCalls int64
+}
+
+// Add returns the sum of both counts
+func (c WindowCounts) Add(other WindowCounts) WindowCounts {
+	return WindowCounts{
+		Calls:     c.Calls + other.Calls,
+		Failures:  c.Failures + other.Failures,
+		SlowCalls: c.SlowCalls + other.SlowCalls,
+	}
+}
+
+// Rates returns the failure rate and slow call rate as ratios from 0 to 1
+func (c WindowCounts) Rates() (failureRate, slowCallRate float64) {
+	if c.Calls == 0 {
+		return 0, 0
+	}
+
+	return float64(c.Failures) / float64(c.Calls), float64(c.SlowCalls) / float64(c.Calls)
+}
+
+func windowCounts(w Window) WindowCounts {
+	rates := w.CallRates()
+	calls := float64(rates.TotalCalls)
+	return WindowCounts{
+		Calls:     int64(rates.TotalCalls),
+		Failures:  int64(math.Round(rates.FailureRate * calls)),
+		SlowCalls: int64(math.Round(rates.SlowCallRate * calls)),
+	}
+}
+
+// StateStore shares circuit breaker state and window counts between instances of the same
+// circuit breaker, e.g. replicas of a service calling the same dependency.
+//
+// Conflicts are resolved with optimistic concurrency: a state is only stored if the stored
+// version is the one the instance last saw, otherwise the stored state wins and the instance
+// adopts it. Counts are reported against the state version they were recorded under, so counts
+// from before a transition are never aggregated with counts after it.
+type StateStore interface {
+	// GetState returns the stored state for name, ok is false if none was stored yet
+	GetState(ctx context.Context, name string) (state SharedState, ok bool, err error)
+
+	// CompareAndSwapState stores state with version expected+1 if the stored version is expected,
+	// where a missing state has version zero. It returns the stored state after the operation
+	// and whether the swap happened.
+	CompareAndSwapState(ctx context.Context, name string, expected uint64, state SharedState) (
+		current SharedState, swapped bool, err error,
+	)
+
+	// ReportCounts replaces the counts of instance for name, recorded under the state version
+	ReportCounts(ctx context.Context, name string, instance string, version uint64, counts WindowCounts) error
+
+	// AggregatedCounts sums the counts for name reported under version no longer than maxAge ago
+	AggregatedCounts(ctx context.Context, name string, version uint64, maxAge time.Duration) (WindowCounts, error)
+}
+
+type reportedCounts struct {
+	Version    uint64
+	Counts     WindowCounts
+	ReportedAt time.Time
+}
+
+type sharedBreaker struct {
+	State  SharedState
+	Counts map[string]reportedCounts
+}
+
+func (b *sharedBreaker) compareAndSwap(expected uint64, state SharedState) (SharedState, bool) {
+	if b.State.Version != expected {
+		return b.State, false
+	}
+
+	state.Version = expected + 1
+	b.State = state
+	return state, true
+}
+
+func (b *sharedBreaker) report(instance string, version uint64, counts WindowCounts, now time.Time) {
+	if b.Counts == nil {
+		b.Counts = make(map[string]reportedCounts)
+	}
+
+	b.Counts[instance] = reportedCounts{
+		Version:    version,
+		Counts:     counts,
+		ReportedAt: now,
+	}
+}
+
+func (b *sharedBreaker) aggregate(version uint64, maxAge time.Duration, now time.Time) WindowCounts {
+	var total WindowCounts
+	for _, reported := range b.Counts {
+		if reported.Version == version && now.Sub(reported.ReportedAt) <= maxAge {
+			total = total.Add(reported.Counts)
+		}
+	}
+
+	return total
+}
+
+var _ StateStore = (*MemoryStateStore)(nil)
+
+// MemoryStateStore is a StateStore for circuit breakers within a single process
+type MemoryStateStore struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*sharedBreaker
+}
+
+type StateStoreOption func(*stateStoreConfig)
+
+type stateStoreConfig struct {
+	now     func() time.Time
+	lockTTL time.Duration
+}
+
+// WithStateStoreClock replaces the clock the store uses to timestamp and age reported counts
+func WithStateStoreClock(now func() time.Time) StateStoreOption {
+	return func(c *stateStoreConfig) {
+		c.now = now
+	}
+}
+
+// WithFileLockTTL sets the age after which the lock file of a FileStateStore is considered left
+// behind by a crashed process and taken over, it has to be well above the time an operation takes
+func WithFileLockTTL(ttl time.Duration) StateStoreOption {
+	return func(c *stateStoreConfig) {
+		c.lockTTL = ttl
+	}
+}
+
+func newStateStoreConfig(opts []StateStoreOption) stateStoreConfig {
+	config := stateStoreConfig{now: time.Now, lockTTL: defaultFileLockTTL}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return config
+}
+
+func NewMemoryStateStore(opts ...StateStoreOption) *MemoryStateStore {
+	config := newStateStoreConfig(opts)
+
+	return &MemoryStateStore{
+		now:      config.now,
+		breakers: make(map[string]*sharedBreaker),
+	}
+}
+
+func (s *MemoryStateStore) breakerUnsafe(name string) *sharedBreaker {
+	b, ok := s.breakers[name]
+	if !ok {
+		b = &sharedBreaker{}
+		s.breakers[name] = b
+	}
+
+	return b
+}
+
+func (s *MemoryStateStore) GetState(_ context.Context, name string) (SharedState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.breakers[name]
+	if !ok || b.State.Version == 0 {
+		return SharedState{}, false, nil
+	}
+
+	return b.State, true, nil
+}
+
+func (s *MemoryStateStore) CompareAndSwapState(_ context.Context, name string, expected uint64, state SharedState) (
+	SharedState, bool, error,
+) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, swapped := s.breakerUnsafe(name).compareAndSwap(expected, state)
+	return current, swapped, nil
+}
+
+func (s *MemoryStateStore) ReportCounts(
+	_ context.Context, name string, instance string, version uint64, counts WindowCounts,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.breakerUnsafe(name).report(instance, version, counts, s.now())
+	return nil
+}
+
+func (s *MemoryStateStore) AggregatedCounts(
+	_ context.Context, name string, version uint64, maxAge time.Duration,
+) (WindowCounts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.breakers[name]
+	if !ok {
+		return WindowCounts{}, nil
+	}
+
+	return b.aggregate(version, maxAge, s.now()), nil
+}
@@ -0,0 +1,202 @@
+package circuitbreaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var _ StateStore = (*FileStateStore)(nil)
+
+const (
+	// fileLockRetryInterval is how often a locked file is checked again
+	fileLockRetryInterval = time.Millisecond
+
+	defaultFileLockTTL = 10 * time.Second
+)
+
+// FileStateStore is a StateStore backed by a JSON file, for sharing state between processes on
+// the same machine, e.g. in local testing. Every operation holds a lock file next to the state
+// file, a lock older than the lock TTL is considered left behind by a crashed process and taken
+// over, see WithFileLockTTL.
+type FileStateStore struct {
+	path    string
+	now     func() time.Time
+	lockTTL time.Duration
+
+	// mu serializes operations within the process, the lock file across processes
+	mu sync.Mutex
+}
+
+func NewFileStateStore(path string, opts ...StateStoreOption) *FileStateStore {
+	config := newStateStoreConfig(opts)
+
+	return &FileStateStore{
+		path:    path,
+		now:     config.now,
+		lockTTL: config.lockTTL,
+	}
+}
+
+func (s *FileStateStore) GetState(ctx context.Context, name string) (state SharedState, ok bool, err error) {
+	err = s.update(
+		ctx, false, func(breakers map[string]*sharedBreaker) {
+			if b, found := breakers[name]; found && b.State.Version > 0 {
+				state, ok = b.State, true
+			}
+		},
+	)
+
+	return state, ok, err
+}
+
+func (s *FileStateStore) CompareAndSwapState(ctx context.Context, name string, expected uint64, state SharedState) (
+	current SharedState, swapped bool, err error,
+) {
+	err = s.update(
+		ctx, true, func(breakers map[string]*sharedBreaker) {
+			current, swapped = breakerOf(breakers, name).compareAndSwap(expected, state)
+		},
+	)
+
+	return current, swapped, err
+}
+
+func (s *FileStateStore) ReportCounts(
+	ctx context.Context, name string, instance string, version uint64, counts WindowCounts,
+) error {
+	return s.update(
+		ctx, true, func(breakers map[string]*sharedBreaker) {
+			breakerOf(breakers, name).report(instance, version, counts, s.now())
+		},
+	)
+}
+
+func (s *FileStateStore) AggregatedCounts(
+	ctx context.Context, name string, version uint64, maxAge time.Duration,
+) (counts WindowCounts, err error) {
+	err = s.update(
+		ctx, false, func(breakers map[string]*sharedBreaker) {
+			if b, ok := breakers[name]; ok {
+				counts = b.aggregate(version, maxAge, s.now())
+			}
+		},
+	)
+
+	return counts, err
+}
+
+func breakerOf(breakers map[string]*sharedBreaker, name string) *sharedBreaker {
+	b, ok := breakers[name]
+	if !ok {
+		b = &sharedBreaker{}
+		breakers[name] = b
+	}
+
+	return b
+}
+
+// update runs fn on the stored breakers while holding the lock, writing them back if write is set
+func (s *FileStateStore) update(ctx context.Context, write bool, fn func(map[string]*sharedBreaker)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	breakers := make(map[string]*sharedBreaker)
+
+	data, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("circuitbreaker: failed to read state file: %w", err)
+	case len(data) > 0:
+		if err := json.Unmarshal(data, &breakers); err != nil {
+			return fmt.Errorf("circuitbreaker: failed to decode state file: %w", err)
+		}
+	}
+
+	fn(breakers)
+
+	if !write {
+		return nil
+	}
+
+	data, err = json.Marshal(breakers)
+	if err != nil {
+		return fmt.Errorf("circuitbreaker: failed to encode state file: %w", err)
+	}
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("circuitbreaker: failed to write state file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *FileStateStore) lock(ctx context.Context) (func(), error) {
+	lockPath := s.path + ".lock"
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("circuitbreaker: failed to lock state file: %w", err)
+		}
+
+		if s.removeStaleLock(lockPath) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("circuitbreaker: failed to lock state file: %w", ctx.Err())
+		case <-time.After(fileLockRetryInterval):
+		}
+	}
+}
+
+// removeStaleLock removes the lock file if it is older than the lock TTL and returns whether it
+// did. The age is measured with the wall clock the file system uses, not the store clock. Two
+// processes taking over the same stale lock at once may both proceed, which only happens after a
+// crash and is acceptable for the local use the store is meant for.
+func (s *FileStateStore) removeStaleLock(lockPath string) bool {
+	info, err := os.Stat(lockPath)
+	if err != nil || time.Since(info.ModTime()) < s.lockTTL {
+		return false
+	}
+
+	return os.Remove(lockPath) == nil
+}
+
+// writeFileAtomic writes to a temporary file and renames it, so readers never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,288 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+type storeFactory func(t *testing.T, now func() time.Time) circuitbreaker.StateStore
+
+var stateStores = map[string]storeFactory{
+	"memory": func(_ *testing.T, now func() time.Time) circuitbreaker.StateStore {
+		return circuitbreaker.NewMemoryStateStore(circuitbreaker.WithStateStoreClock(now))
+	},
+	"file": func(t *testing.T, now func() time.Time) circuitbreaker.StateStore {
+		path := filepath.Join(t.TempDir(), "state.json")
+		return circuitbreaker.NewFileStateStore(path, circuitbreaker.WithStateStoreClock(now))
+	},
+}
+
+func sharedBreaker(
+	t *testing.T, store circuitbreaker.StateStore, instance string, opts ...circuitbreaker.Option,
+) circuitbreaker.CircuitBreaker {
+	opts = append(
+		[]circuitbreaker.Option{
+			circuitbreaker.WithStateStore(store, instance),
+			circuitbreaker.WithStateSyncInterval(0),
+			circuitbreaker.WithWindow(circuitbreaker.NewCountWindow(10)),
+			circuitbreaker.WithMinimumNumberOfCalls(4),
+			circuitbreaker.WithFailureRateThreshold(50),
+		}, opts...,
+	)
+
+	cb := circuitbreaker.New("shared", opts...)
+	t.Cleanup(func() { require.NoError(t, cb.Close()) })
+
+	return cb
+}
+
+// eventuallyRejected calls cb until a background sync made it adopt the open state
+func eventuallyRejected(t *testing.T, cb circuitbreaker.CircuitBreaker) {
+	t.Helper()
+
+	require.Eventually(
+		t, func() bool {
+			return circuitbreaker.IsCallNotPermittedError(circuitbreaker.Do(context.Background(), cb, succeedingCall))
+		}, time.Second, time.Millisecond,
+	)
+}
+
+func succeedingCall(_ context.Context) error {
+	return nil
+}
+
+func TestStateStore_CompareAndSwap(t *testing.T) {
+	for name, newStore := range stateStores {
+		t.Run(
+			name, func(t *testing.T) {
+				ctx := context.Background()
+				store := newStore(t, time.Now)
+
+				_, ok, err := store.GetState(ctx, "shared")
+				require.NoError(t, err)
+				require.False(t, ok)
+
+				current, swapped, err := store.CompareAndSwapState(
+					ctx, "shared", 0, circuitbreaker.SharedState{State: circuitbreaker.StateOpen, UpdatedBy: "a"},
+				)
+				require.NoError(t, err)
+				require.True(t, swapped)
+				require.Equal(t, uint64(1), current.Version)
+
+				// the second writer based on the same version loses and gets the stored state back
+				current, swapped, err = store.CompareAndSwapState(
+					ctx, "shared", 0, circuitbreaker.SharedState{State: circuitbreaker.StateClosed, UpdatedBy: "b"},
+				)
+				require.NoError(t, err)
+				require.False(t, swapped)
+				require.Equal(t, circuitbreaker.StateOpen, current.State)
+				require.Equal(t, "a", current.UpdatedBy)
+
+				stored, ok, err := store.GetState(ctx, "shared")
+				require.NoError(t, err)
+				require.True(t, ok)
+				require.Equal(t, current, stored)
+			},
+		)
+	}
+}
+
+func TestStateStore_AggregatedCountsStaleness(t *testing.T) {
+	for name, newStore := range stateStores {
+		t.Run(
+			name, func(t *testing.T) {
+				ctx := context.Background()
+				clock := &fakeClock{now: time.Unix(1000, 0)}
+				store := newStore(t, clock.Now)
+
+				require.NoError(t, store.ReportCounts(ctx, "shared", "a", 1, circuitbreaker.WindowCounts{Calls: 4, Failures: 4}))
+				clock.Advance(5 * time.Second)
+				require.NoError(t, store.ReportCounts(ctx, "shared", "b", 1, circuitbreaker.WindowCounts{Calls: 2, Failures: 1}))
+				// counts recorded before the last transition are never aggregated
+				require.NoError(t, store.ReportCounts(ctx, "shared", "c", 0, circuitbreaker.WindowCounts{Calls: 8, Failures: 8}))
+
+				counts, err := store.AggregatedCounts(ctx, "shared", 1, 10*time.Second)
+				require.NoError(t, err)
+				require.Equal(t, circuitbreaker.WindowCounts{Calls: 6, Failures: 5}, counts)
+
+				// a stopped reporting and ages out
+				clock.Advance(6 * time.Second)
+				counts, err = store.AggregatedCounts(ctx, "shared", 1, 10*time.Second)
+				require.NoError(t, err)
+				require.Equal(t, circuitbreaker.WindowCounts{Calls: 2, Failures: 1}, counts)
+
+				// reports replace the previous counts of the instance
+				require.NoError(t, store.ReportCounts(ctx, "shared", "b", 1, circuitbreaker.WindowCounts{Calls: 3}))
+				counts, err = store.AggregatedCounts(ctx, "shared", 1, 10*time.Second)
+				require.NoError(t, err)
+				require.Equal(t, circuitbreaker.WindowCounts{Calls: 3}, counts)
+			},
+		)
+	}
+}
+
+func TestCircuitBreaker_SharedStatePropagatesOpen(t *testing.T) {
+	for name, newStore := range stateStores {
+		t.Run(
+			name, func(t *testing.T) {
+				store := newStore(t, time.Now)
+				a := sharedBreaker(t, store, "a")
+				b := sharedBreaker(t, store, "b")
+
+				require.NoError(t, circuitbreaker.Do(context.Background(), b, succeedingCall))
+
+				for a.State() == circuitbreaker.StateClosed {
+					require.ErrorIs(t, circuitbreaker.Do(context.Background(), a, failingCall), errDependency)
+				}
+
+				eventuallyRejected(t, b)
+				require.Equal(t, circuitbreaker.StateOpen, b.State())
+
+				// the wait in the open state is measured from the original transition
+				require.Equal(t, a.Metrics().TransitionTime.UnixNano(), b.Metrics().TransitionTime.UnixNano())
+			},
+		)
+	}
+}
+
+func TestCircuitBreaker_SharedStateTripsOnAggregatedCounts(t *testing.T) {
+	store := circuitbreaker.NewMemoryStateStore()
+	a := sharedBreaker(t, store, "a")
+	b := sharedBreaker(t, store, "b")
+
+	// neither instance reaches the minimum number of calls on its own
+	for _, cb := range []circuitbreaker.CircuitBreaker{a, a, b, b} {
+		require.ErrorIs(t, circuitbreaker.Do(context.Background(), cb, failingCall), errDependency)
+
+		// waits for the sync started by the call, so every call reports its counts
+		require.NoError(t, cb.Close())
+	}
+
+	require.Equal(t, circuitbreaker.StateOpen, b.State())
+	eventuallyRejected(t, a)
+}
+
+func TestCircuitBreaker_SharedStateConflictAdoptsStoredState(t *testing.T) {
+	store := circuitbreaker.NewMemoryStateStore()
+	a := sharedBreaker(t, store, "a")
+	// b only syncs when it transitions, so it does not see a trip first
+	b := sharedBreaker(t, store, "b", circuitbreaker.WithStateSyncInterval(time.Hour))
+
+	require.NoError(t, circuitbreaker.Do(context.Background(), a, succeedingCall))
+	require.NoError(t, circuitbreaker.Do(context.Background(), b, succeedingCall))
+	require.NoError(t, b.Close())
+
+	for i := 0; i < 4; i++ {
+		_ = circuitbreaker.Do(context.Background(), a, failingCall)
+	}
+	require.Equal(t, circuitbreaker.StateOpen, a.State())
+	require.NoError(t, a.Close())
+
+	for b.State() == circuitbreaker.StateClosed {
+		require.ErrorIs(t, circuitbreaker.Do(context.Background(), b, failingCall), errDependency)
+	}
+	require.NoError(t, b.Close())
+
+	// b's own transition lost the compare-and-swap, so it adopted the transition stored by a
+	stored, ok, err := store.GetState(context.Background(), "shared")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "a", stored.UpdatedBy)
+	require.Equal(t, uint64(2), stored.Version)
+	require.Equal(t, circuitbreaker.StateOpen, b.State())
+	require.Equal(t, a.Metrics().TransitionTime.UnixNano(), b.Metrics().TransitionTime.UnixNano())
+}
+
+func TestCircuitBreaker_SharedStateStalenessBound(t *testing.T) {
+	store := circuitbreaker.NewMemoryStateStore()
+	a := sharedBreaker(t, store, "a")
+	b := sharedBreaker(t, store, "b", circuitbreaker.WithStateSyncInterval(50*time.Millisecond))
+
+	require.NoError(t, circuitbreaker.Do(context.Background(), b, succeedingCall))
+	require.NoError(t, b.Close())
+
+	for i := 0; i < 4; i++ {
+		_ = circuitbreaker.Do(context.Background(), a, failingCall)
+	}
+	require.NoError(t, a.Close())
+
+	// within the sync interval b still works on its local state
+	require.NoError(t, circuitbreaker.Do(context.Background(), b, succeedingCall))
+
+	time.Sleep(60 * time.Millisecond)
+	eventuallyRejected(t, b)
+}
+
+func TestCircuitBreaker_SharedStateDoesNotBlockCalls(t *testing.T) {
+	store := &blockingStateStore{StateStore: circuitbreaker.NewMemoryStateStore(), release: make(chan struct{})}
+	cb := sharedBreaker(t, store, "a", circuitbreaker.WithStateSyncTimeout(time.Hour))
+	defer close(store.release)
+
+	// the store does not answer, calls carry on with the local state
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 10 {
+			_ = circuitbreaker.Do(context.Background(), cb, succeedingCall)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "calls waited for the state store")
+	}
+}
+
+type blockingStateStore struct {
+	circuitbreaker.StateStore
+	release chan struct{}
+}
+
+func (s *blockingStateStore) GetState(ctx context.Context, name string) (circuitbreaker.SharedState, bool, error) {
+	<-s.release
+	return s.StateStore.GetState(ctx, name)
+}
+
+func (s *blockingStateStore) CompareAndSwapState(
+	ctx context.Context, name string, expected uint64, state circuitbreaker.SharedState,
+) (circuitbreaker.SharedState, bool, error) {
+	<-s.release
+	return s.StateStore.CompareAndSwapState(ctx, name, expected, state)
+}
+
+func TestFileStateStore_TakesOverStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path+".lock", nil, 0o600))
+
+	// a fresh lock is respected until the operation times out, whatever the store clock says
+	ahead := func() time.Time { return time.Now().Add(time.Hour) }
+	store := circuitbreaker.NewFileStateStore(
+		path, circuitbreaker.WithFileLockTTL(time.Minute), circuitbreaker.WithStateStoreClock(ahead),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, _, err := store.GetState(ctx, "shared")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// once the lock is older than the TTL it was left behind by a crashed process
+	stale := time.Now().Add(-2 * time.Minute)
+	require.NoError(t, os.Chtimes(path+".lock", stale, stale))
+
+	_, swapped, err := store.CompareAndSwapState(
+		context.Background(), "shared", 0, circuitbreaker.SharedState{State: circuitbreaker.StateOpen},
+	)
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	_, err = os.Stat(path + ".lock")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
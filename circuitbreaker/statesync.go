@@ -0,0 +1,218 @@
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// requestSyncUnsafe starts a sync with the StateStore in the background once one is due, so calls
+// never wait for the store and carry on with the local state until the sync updated it. Only one
+// sync runs at a time, it syncs again when a transition was made while it was running.
+func (cb *circuitBreakerImpl) requestSyncUnsafe() {
+	if cb.config.StateStore == nil || cb.state == StateMetricsOnly {
+		return
+	}
+
+	now := cb.config.Clock.Now()
+	if !cb.stateDirty && now.Sub(cb.lastStateSync) < cb.config.StateSyncInterval {
+		return
+	}
+
+	if !cb.syncMu.TryLock() {
+		return
+	}
+	cb.lastStateSync = now
+
+	go func() {
+		defer cb.syncMu.Unlock()
+		for cb.syncState() {
+		}
+	}()
+}
+
+// waitForSync blocks until a running sync completed, it must be called without holding cb.mu
+func (cb *circuitBreakerImpl) waitForSync() {
+	cb.syncMu.Lock()
+	defer cb.syncMu.Unlock()
+}
+
+// syncState publishes local transitions to the StateStore, adopts newer shared states and
+// trips the circuit breaker when the counts aggregated over all instances exceed the thresholds.
+// It runs while holding cb.syncMu and without holding cb.mu, and returns whether a transition
+// made during the sync is left to publish.
+func (cb *circuitBreakerImpl) syncState() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), cb.config.StateSyncTimeout)
+	defer cancel()
+
+	cb.mu.Lock()
+	transitions := cb.transitions
+	cb.mu.Unlock()
+
+	if err := cb.syncSharedState(ctx); err != nil {
+		return false
+	}
+
+	if cb.shareCounts(ctx) {
+		transitions++
+		if err := cb.syncSharedState(ctx); err != nil {
+			return false
+		}
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if !cb.stateDirty || cb.transitions == transitions {
+		return false
+	}
+
+	cb.lastStateSync = cb.config.Clock.Now()
+	return true
+}
+
+// syncSharedState publishes the local state if it changed since the last sync, otherwise it
+// fetches the shared state. When the stored version moved on, the stored state wins.
+func (cb *circuitBreakerImpl) syncSharedState(ctx context.Context) error {
+	store := cb.config.StateStore
+
+	cb.mu.Lock()
+	transitions := cb.transitions
+	version := cb.sharedVersion
+	dirty := cb.stateDirty
+	desired := SharedState{
+		State:          cb.state,
+		TransitionTime: cb.transitionTime,
+		UpdatedBy:      cb.config.InstanceID,
+	}
+	cb.mu.Unlock()
+
+	var current SharedState
+	if dirty {
+		stored, swapped, err := store.CompareAndSwapState(ctx, cb.name, version, desired)
+		if err == nil && !swapped && tripsAfter(desired, stored) {
+			// the local trip is based on calls made after the stored transition, so it is not discarded
+			stored, swapped, err = store.CompareAndSwapState(ctx, cb.name, stored.Version, desired)
+		}
+		if err != nil {
+			return err
+		}
+
+		if swapped {
+			cb.mu.Lock()
+			cb.sharedVersion = stored.Version
+			// a transition that happened during the swap is published on the next sync
+			if cb.transitions == transitions {
+				cb.stateDirty = false
+			}
+			cb.mu.Unlock()
+			return nil
+		}
+
+		current = stored
+	} else {
+		stored, ok, err := store.GetState(ctx, cb.name)
+		if err != nil || !ok {
+			return err
+		}
+
+		current = stored
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	// a transition made during the sync is published by the next one rather than overwritten
+	if cb.transitions == transitions {
+		cb.adoptSharedStateUnsafe(current)
+	}
+	return nil
+}
+
+// tripsAfter reports whether local opens the circuit breaker after the transition to stored
+func tripsAfter(local, stored SharedState) bool {
+	return local.State == StateOpen && stored.State != StateOpen && local.TransitionTime.After(stored.TransitionTime)
+}
+
+func (cb *circuitBreakerImpl) adoptSharedStateUnsafe(shared SharedState) {
+	if shared.Version < cb.sharedVersion {
+		// the store lost its state, e.g. it was restarted, so publish the local state again
+		cb.sharedVersion = shared.Version
+		cb.stateDirty = true
+		return
+	}
+
+	if shared.Version == cb.sharedVersion {
+		return
+	}
+
+	cb.setStateUnsafe(shared.State)
+	cb.transitionTime = shared.TransitionTime
+	cb.scheduleStateTimerUnsafe()
+	cb.sharedVersion = shared.Version
+	cb.stateDirty = false
+}
+
+// shareCounts reports the local window counts and trips the circuit breaker if the counts of
+// all instances exceed the thresholds, it returns whether it did
+func (cb *circuitBreakerImpl) shareCounts(ctx context.Context) bool {
+	store := cb.config.StateStore
+
+	cb.mu.Lock()
+	version := cb.sharedVersion
+	state := cb.state
+	dirty := cb.stateDirty
+	counts := windowCounts(cb.window)
+	cb.mu.Unlock()
+
+	// counts recorded under a state that is not published yet would be reported against the wrong version
+	if dirty {
+		return false
+	}
+
+	if err := store.ReportCounts(ctx, cb.name, cb.config.InstanceID, version, counts); err != nil {
+		return false
+	}
+
+	if state != StateClosed {
+		return false
+	}
+
+	aggregated, err := store.AggregatedCounts(ctx, cb.name, version, cb.config.SharedCountsMaxAge)
+	if err != nil {
+		return false
+	}
+
+	// consecutive outcomes and category rates are local to an instance, only the aggregated rates are evaluated
+	failureRate, slowCallRate := aggregated.Rates()
+	rates := CallRates{
+		TotalCalls:   int(aggregated.Calls),
+		FailureRate:  failureRate,
+		SlowCallRate: slowCallRate,
+	}
+	rates.setPercents()
+
+	next := cb.config.TripStrategy.Evaluate(TripEvaluation{State: StateClosed, CallRates: rates})
+	if next != StateOpen {
+		return false
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != StateClosed || cb.sharedVersion != version {
+		return false
+	}
+
+	cb.setStateUnsafe(StateOpen)
+	return true
+}
@@ -0,0 +1,183 @@
+package circuitbreaker
+
+// TripEvaluation is what a TripStrategy decides on after a call outcome was recorded
+type TripEvaluation struct {
+	// State is the current state, StateClosed or StateHalfOpen
+	State State
+
+	// CallRates are the rates of the calls in the window, its Name is left empty
+	CallRates
+
+	// ConsecutiveFailures and ConsecutiveSuccesses count the calls since the last outcome of
+	// the other kind, both are reset on every state transition
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+
+	// HalfOpenCompletedCalls is the number of calls completed in the half-open state out of
+	// the HalfOpenPermittedCalls it permits
+	HalfOpenCompletedCalls int
+	HalfOpenPermittedCalls int
+}
+
+// TripStrategy decides the state transitions of a circuit breaker in the closed and half-open
+// states. Evaluate returns StateOpen to open the circuit breaker, StateClosed to close it, or
+// the current state to stay in it. Strategies must not keep state of their own, so a single
+// strategy can be shared by many circuit breakers.
+type TripStrategy interface {
+	Evaluate(evaluation TripEvaluation) State
+}
+
+var _ TripStrategy = (*RateTripStrategy)(nil)
+
+// RateTripStrategy opens the circuit breaker when the failure rate, slow call rate or the failure
+// rate of a category in CategoryRateThresholds reaches its threshold once the window holds
+// MinimumNumberOfCalls. In the half-open state it decides once every permitted call completed.
+type RateTripStrategy struct {
+	MinimumNumberOfCalls  int
+	FailureRateThreshold  float64
+	SlowCallRateThreshold float64
+
+	// CategoryRateThresholds are thresholds in percentage on the failure rate of single categories,
+	// e.g. to open on a lower rate of timeouts than of other errors
+	CategoryRateThresholds map[FailureCategory]float64
+}
+
+func NewRateTripStrategy(minimumNumberOfCalls int, failureRateThreshold, slowCallRateThreshold float64) *RateTripStrategy {
+	return &RateTripStrategy{
+		MinimumNumberOfCalls:  minimumNumberOfCalls,
+		FailureRateThreshold:  failureRateThreshold,
+		SlowCallRateThreshold: slowCallRateThreshold,
+	}
+}
+
+func (s *RateTripStrategy) Evaluate(e TripEvaluation) State {
+	exceeded := s.exceeded(e.CallRates)
+
+	switch e.State {
+	case StateClosed:
+		if e.TotalCalls >= s.MinimumNumberOfCalls && exceeded {
+			return StateOpen
+		}
+	case StateHalfOpen:
+		if e.HalfOpenCompletedCalls < e.HalfOpenPermittedCalls {
+			return StateHalfOpen
+		}
+
+		if exceeded {
+			return StateOpen
+		}
+
+		return StateClosed
+	default:
+	}
+
+	return e.State
+}
+
+func (s *RateTripStrategy) exceeded(rates CallRates) bool {
+	if rates.FailureRatePercent >= s.FailureRateThreshold || rates.SlowCallRatePercent >= s.SlowCallRateThreshold {
+		return true
+	}
+
+	for category, threshold := range s.CategoryRateThresholds {
+		if rates.CategoryRatesPercent[category] >= threshold {
+			return true
+		}
+	}
+
+	return false
+}
+
+var _ TripStrategy = (*ConsecutiveTripStrategy)(nil)
+
+// ConsecutiveTripStrategy opens the circuit breaker after FailureThreshold consecutive failures,
+// independent of the call volume. In the half-open state any failure opens it again and it
+// closes after SuccessThreshold consecutive successes, or once every permitted call succeeded
+// if fewer calls are permitted.
+type ConsecutiveTripStrategy struct {
+	FailureThreshold int
+	SuccessThreshold int
+}
+
+func NewConsecutiveTripStrategy(failureThreshold, successThreshold int) *ConsecutiveTripStrategy {
+	return &ConsecutiveTripStrategy{
+		FailureThreshold: failureThreshold,
+		SuccessThreshold: successThreshold,
+	}
+}
+
+func (s *ConsecutiveTripStrategy) Evaluate(e TripEvaluation) State {
+	switch e.State {
+	case StateClosed:
+		if e.ConsecutiveFailures >= s.FailureThreshold {
+			return StateOpen
+		}
+	case StateHalfOpen:
+		if e.ConsecutiveFailures > 0 {
+			return StateOpen
+		}
+
+		if e.ConsecutiveSuccesses >= min(s.SuccessThreshold, e.HalfOpenPermittedCalls) {
+			return StateClosed
+		}
+	default:
+	}
+
+	return e.State
+}
+
+var _ TripStrategy = (*AnyTripStrategy)(nil)
+
+// AnyTripStrategy opens the circuit breaker as soon as any of its strategies opens it and
+// only closes it from the half-open state once all of them close it
+type AnyTripStrategy struct {
+	Strategies []TripStrategy
+}
+
+func NewAnyTripStrategy(strategies ...TripStrategy) *AnyTripStrategy {
+	return &AnyTripStrategy{Strategies: strategies}
+}
+
+func (s *AnyTripStrategy) Evaluate(e TripEvaluation) State {
+	return combine(s.Strategies, e, StateOpen, StateClosed)
+}
+
+var _ TripStrategy = (*AllTripStrategy)(nil)
+
+// AllTripStrategy only opens the circuit breaker once all of its strategies open it and
+// closes it from the half-open state as soon as any of them closes it
+type AllTripStrategy struct {
+	Strategies []TripStrategy
+}
+
+func NewAllTripStrategy(strategies ...TripStrategy) *AllTripStrategy {
+	return &AllTripStrategy{Strategies: strategies}
+}
+
+func (s *AllTripStrategy) Evaluate(e TripEvaluation) State {
+	return combine(s.Strategies, e, StateClosed, StateOpen)
+}
+
+// combine returns eager as soon as any strategy returns it, and reluctant only when all do
+func combine(strategies []TripStrategy, e TripEvaluation, eager, reluctant State) State {
+	if len(strategies) == 0 {
+		return e.State
+	}
+
+	all := true
+	for _, strategy := range strategies {
+		switch strategy.Evaluate(e) {
+		case eager:
+			return eager
+		case reluctant:
+		default:
+			all = false
+		}
+	}
+
+	if all {
+		return reluctant
+	}
+
+	return e.State
+}
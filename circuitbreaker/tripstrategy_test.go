@@ -0,0 +1,78 @@
+package circuitbreaker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+func TestCircuitBreaker_ConsecutiveTripStrategy(t *testing.T) {
+	cb := circuitbreaker.New(
+		"test",
+		circuitbreaker.WithTripStrategy(circuitbreaker.NewConsecutiveTripStrategy(3, 2)),
+		circuitbreaker.WithPermittedNumberOfCallsInHalfOpenState(5),
+	)
+
+	// a success resets the count, the rate based minimum number of calls does not apply
+	for _, call := range []func(context.Context) error{failingCall, failingCall, succeedingCall, failingCall, failingCall} {
+		_ = circuitbreaker.Do(context.Background(), cb, call)
+	}
+	require.Equal(t, circuitbreaker.StateClosed, cb.State())
+
+	_ = circuitbreaker.Do(context.Background(), cb, failingCall)
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+
+	halfOpen := func() {
+		snapshot := cb.Snapshot()
+		snapshot.TransitionTime = time.Now().Add(-time.Hour)
+		require.NoError(t, cb.Restore(snapshot))
+	}
+
+	// any failure in the half-open state opens the circuit breaker again
+	halfOpen()
+	require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+	require.Equal(t, circuitbreaker.StateHalfOpen, cb.State())
+	_ = circuitbreaker.Do(context.Background(), cb, failingCall)
+	require.Equal(t, circuitbreaker.StateOpen, cb.State())
+
+	halfOpen()
+	require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+	require.NoError(t, circuitbreaker.Do(context.Background(), cb, succeedingCall))
+	require.Equal(t, circuitbreaker.StateClosed, cb.State())
+}
+
+func TestTripStrategy_Composite(t *testing.T) {
+	rate := circuitbreaker.NewRateTripStrategy(10, 50, 100)
+	consecutive := circuitbreaker.NewConsecutiveTripStrategy(3, 2)
+
+	// three consecutive failures at low volume
+	lowVolume := circuitbreaker.TripEvaluation{
+		State:               circuitbreaker.StateClosed,
+		CallRates:           circuitbreaker.CallRates{TotalCalls: 3, FailureRate: 1, FailureRatePercent: 100},
+		ConsecutiveFailures: 3,
+	}
+	require.Equal(t, circuitbreaker.StateOpen, circuitbreaker.NewAnyTripStrategy(rate, consecutive).Evaluate(lowVolume))
+	require.Equal(t, circuitbreaker.StateClosed, circuitbreaker.NewAllTripStrategy(rate, consecutive).Evaluate(lowVolume))
+
+	highVolume := lowVolume
+	highVolume.TotalCalls = 10
+	require.Equal(t, circuitbreaker.StateOpen, circuitbreaker.NewAllTripStrategy(rate, consecutive).Evaluate(highVolume))
+
+	// two successes into the half-open state, with more permitted calls still running
+	halfOpen := circuitbreaker.TripEvaluation{
+		State:                  circuitbreaker.StateHalfOpen,
+		CallRates:              circuitbreaker.CallRates{TotalCalls: 2},
+		ConsecutiveSuccesses:   2,
+		HalfOpenCompletedCalls: 2,
+		HalfOpenPermittedCalls: 5,
+	}
+	require.Equal(t, circuitbreaker.StateHalfOpen, circuitbreaker.NewAnyTripStrategy(rate, consecutive).Evaluate(halfOpen))
+	require.Equal(t, circuitbreaker.StateClosed, circuitbreaker.NewAllTripStrategy(rate, consecutive).Evaluate(halfOpen))
+
+	halfOpen.HalfOpenCompletedCalls = 5
+	require.Equal(t, circuitbreaker.StateClosed, circuitbreaker.NewAnyTripStrategy(rate, consecutive).Evaluate(halfOpen))
+}
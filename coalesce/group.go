@@ -0,0 +1,190 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+type PanicError struct {
+	Recover any
+	Stack   []byte
+}
+
+func (p *PanicError) Error() string {
+	return "coalesce: panic occurred"
+}
+
+func IsPanicError(err error) bool {
+	var panicError *PanicError
+	return errors.As(err, &panicError)
+}
+
+type Config struct {
+	// ResultTTL is how long a successful result is shared with new callers after the call
+	// completed. Zero only shares results with callers that joined while the call was in flight.
+	ResultTTL time.Duration
+}
+
+type Option func(*Config)
+
+func WithResultTTL(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.ResultTTL = ttl
+	}
+}
+
+type call[T any] struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+
+	// waiters is the number of callers waiting on the call, guarded by the group mutex
+	waiters int
+
+	result T
+	err    error
+}
+
+// Group deduplicates concurrent calls by key, so at most one call per key is in flight and
+// all callers for that key share its result.
+//
+// The shared call runs with a context detached from the cancellation of the caller that
+// started it. Each caller can stop waiting by cancelling its own context, and the shared
+// call is only cancelled once every caller has stopped waiting.
+//
+// To retry or protect the shared call, run retry.Execute or circuitbreaker.Execute inside the
+// function passed to Do, so the deduplicated call is what is retried and counted.
+type Group[K comparable, T any] struct {
+	config Config
+
+	mu    sync.Mutex
+	calls map[K]*call[T]
+}
+
+func NewGroup[K comparable, T any](opts ...Option) *Group[K, T] {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &Group[K, T]{
+		config: config,
+		calls:  make(map[K]*call[T]),
+	}
+}
+
+// Do runs fn for key unless a call for key is already in flight or has a shared result,
+// in which case it waits for that call and returns its result
+func (g *Group[K, T]) Do(ctx context.Context, key K, fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	g.mu.Lock()
+	c, ok := g.calls[key]
+	if ok {
+		select {
+		case <-c.done:
+			// a completed call is only kept in the map while its result is shared
+			g.mu.Unlock()
+			return c.result, c.err
+		default:
+		}
+	} else {
+		c = g.startUnsafe(ctx, key, fn)
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.result, c.err
+	case <-ctx.Done():
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		// the call completed while this caller was leaving
+		return c.result, c.err
+	default:
+	}
+
+	c.waiters--
+	if c.waiters == 0 {
+		c.cancel()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+	}
+
+	return zero, ctx.Err()
+}
+
+func (g *Group[K, T]) startUnsafe(ctx context.Context, key K, fn func(context.Context) (T, error)) *call[T] {
+	callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	c := &call[T]{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	g.calls[key] = c
+
+	go g.run(callCtx, key, c, fn)
+
+	return c
+}
+
+func (g *Group[K, T]) run(ctx context.Context, key K, c *call[T], fn func(context.Context) (T, error)) {
+	defer c.cancel()
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				c.err = &PanicError{
+					Recover: r,
+					Stack:   debug.Stack(),
+				}
+			}
+		}()
+
+		c.result, c.err = fn(ctx)
+	}()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	close(c.done)
+
+	if g.calls[key] != c {
+		return
+	}
+
+	if c.err != nil || g.config.ResultTTL <= 0 {
+		delete(g.calls, key)
+		return
+	}
+
+	time.AfterFunc(g.config.ResultTTL, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+	})
+}
+
+// Forget drops the in-flight call or shared result for key, so the next caller starts a new call.
+// Callers already waiting on an in-flight call still receive its result.
+func (g *Group[K, T]) Forget(key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.calls, key)
+}
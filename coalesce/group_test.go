@@ -0,0 +1,171 @@
+package coalesce_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/backoff"
+	"github.com/hugolhafner/dskit/circuitbreaker"
+	"github.com/hugolhafner/dskit/coalesce"
+	"github.com/hugolhafner/dskit/retry"
+)
+
+func TestGroup_DeduplicatesConcurrentCalls(t *testing.T) {
+	g := coalesce.NewGroup[string, int]()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = g.Do(context.Background(), "key", fn)
+		}()
+	}
+
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), calls.Load())
+	for _, r := range results {
+		require.Equal(t, 42, r)
+	}
+}
+
+func TestGroup_CancelsSharedCallWhenAllWaitersLeave(t *testing.T) {
+	g := coalesce.NewGroup[string, int]()
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	fn := func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return 0, ctx.Err()
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := g.Do(first, "key", fn)
+		errs <- err
+	}()
+	<-started
+	go func() {
+		_, err := g.Do(second, "key", fn)
+		errs <- err
+	}()
+	// give the second caller time to join the in-flight call
+	time.Sleep(10 * time.Millisecond)
+
+	cancelFirst()
+	require.ErrorIs(t, <-errs, context.Canceled)
+
+	select {
+	case <-cancelled:
+		t.Fatal("shared call cancelled while a caller is still waiting")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancelSecond()
+	require.ErrorIs(t, <-errs, context.Canceled)
+	<-cancelled
+}
+
+func TestGroup_SharesResultForTTL(t *testing.T) {
+	g := coalesce.NewGroup[string, int](coalesce.WithResultTTL(50 * time.Millisecond))
+
+	var calls atomic.Int32
+	fn := func(context.Context) (int, error) {
+		return int(calls.Add(1)), nil
+	}
+
+	r, err := g.Do(context.Background(), "key", fn)
+	require.NoError(t, err)
+	require.Equal(t, 1, r)
+
+	r, _ = g.Do(context.Background(), "key", fn)
+	require.Equal(t, 1, r)
+
+	require.Eventually(t, func() bool {
+		r, _ = g.Do(context.Background(), "key", fn)
+		return r == 2
+	}, time.Second, 5*time.Millisecond)
+
+	g.Forget("key")
+	r, _ = g.Do(context.Background(), "key", fn)
+	require.Equal(t, 3, r)
+}
+
+func TestGroup_ErrorsAreNotShared(t *testing.T) {
+	g := coalesce.NewGroup[string, int](coalesce.WithResultTTL(time.Minute))
+	errBoom := errors.New("boom")
+
+	_, err := g.Do(context.Background(), "key", func(context.Context) (int, error) { return 0, errBoom })
+	require.ErrorIs(t, err, errBoom)
+
+	r, err := g.Do(context.Background(), "key", func(context.Context) (int, error) { return 1, nil })
+	require.NoError(t, err)
+	require.Equal(t, 1, r)
+
+	_, err = g.Do(context.Background(), "panic", func(context.Context) (int, error) { panic("boom") })
+	require.True(t, coalesce.IsPanicError(err))
+}
+
+func TestGroup_ComposesWithRetryAndCircuitBreaker(t *testing.T) {
+	g := coalesce.NewGroup[string, string]()
+	metrics := circuitbreaker.NewInMemoryMetrics()
+	cb := circuitbreaker.New("coalesced", circuitbreaker.WithMetrics(metrics))
+	policy := retry.MustNewCircuitAwarePolicy("coalesced", retry.WithBackoff(backoff.NewFixed(time.Millisecond)))
+
+	var attempts atomic.Int32
+	release := make(chan struct{})
+	fetch := func(context.Context) (string, error) {
+		<-release
+		if attempts.Add(1) == 1 {
+			return "", errors.New("transient")
+		}
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.Do(context.Background(), "key", func(ctx context.Context) (string, error) {
+				return retry.ExecuteWithCircuit(ctx, policy, cb, fetch)
+			})
+			require.NoError(t, err)
+			require.Equal(t, "value", v)
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(2), attempts.Load())
+
+	stats, _ := metrics.GetMetrics("coalesced")
+	require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeFailure])
+	require.Equal(t, int64(1), stats.Calls[circuitbreaker.OutcomeSuccess])
+}
@@ -0,0 +1,105 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+var _ Limit = (*AIMD)(nil)
+
+// AIMD is a loss based limit that grows by one for every successful sample while the
+// limit is being used and shrinks multiplicatively when a call is dropped or exceeds the timeout.
+// Only calls started after the previous decrease can shrink the limit again, so a burst of
+// drops caused by a single overload backs off once rather than once per dropped call.
+type AIMD struct {
+	mu sync.Mutex
+
+	limit        float64
+	minLimit     float64
+	maxLimit     float64
+	backoffRatio float64
+	timeout      time.Duration
+
+	lastDecrease time.Time
+}
+
+type AIMDOption func(*AIMD)
+
+func WithAIMDInitialLimit(n int) AIMDOption {
+	return func(a *AIMD) {
+		a.limit = float64(n)
+	}
+}
+
+func WithAIMDMinLimit(n int) AIMDOption {
+	return func(a *AIMD) {
+		a.minLimit = float64(n)
+	}
+}
+
+func WithAIMDMaxLimit(n int) AIMDOption {
+	return func(a *AIMD) {
+		a.maxLimit = float64(n)
+	}
+}
+
+// WithAIMDBackoffRatio sets the factor in (0, 1) applied to the limit when a call is dropped
+func WithAIMDBackoffRatio(ratio float64) AIMDOption {
+	return func(a *AIMD) {
+		a.backoffRatio = ratio
+	}
+}
+
+// WithAIMDTimeout sets the round trip time above which a call is treated as dropped
+func WithAIMDTimeout(timeout time.Duration) AIMDOption {
+	return func(a *AIMD) {
+		a.timeout = timeout
+	}
+}
+
+func NewAIMD(opts ...AIMDOption) *AIMD {
+	a := &AIMD{
+		limit:        20,
+		minLimit:     1,
+		maxLimit:     1000,
+		backoffRatio: 0.9,
+		timeout:      5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	a.limit = clampLimit(a.limit, a.minLimit, a.maxLimit)
+
+	return a
+}
+
+func (a *AIMD) EstimatedLimit() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return int(a.limit)
+}
+
+func (a *AIMD) OnSample(startTime time.Time, rtt time.Duration, inflight int, didDrop bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case didDrop || rtt > a.timeout:
+		if startTime.Before(a.lastDecrease) {
+			return
+		}
+
+		a.lastDecrease = startTime.Add(rtt)
+		a.limit = a.limit * a.backoffRatio
+	case float64(inflight)*2 >= a.limit:
+		// only grow while the limit is actually being used
+		a.limit++
+	default:
+		return
+	}
+
+	a.limit = clampLimit(a.limit, a.minLimit, a.maxLimit)
+}
@@ -0,0 +1,43 @@
+package limiter
+
+import (
+	"context"
+)
+
+// Execute runs fn if the limiter has capacity. A nil error is recorded as a success, errors
+// matching DropOnErrorPredicate as dropped and any other error is ignored.
+//
+// To combine with a circuit breaker, run Execute inside circuitbreaker.Execute and add
+// ErrLimitExceeded to the circuit breaker's ignored errors so local rejections do not trip it.
+func Execute[T any](ctx context.Context, l *Limiter, fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+
+	li, err := l.Acquire(ctx)
+	if err != nil {
+		return zero, err
+	}
+
+	// released as ignored if fn panics
+	defer li.OnIgnore()
+
+	result, err := fn(ctx)
+
+	switch {
+	case err == nil:
+		li.OnSuccess()
+	case l.config.DropOnErrorPredicate != nil && l.config.DropOnErrorPredicate(err):
+		li.OnDropped()
+	default:
+		li.OnIgnore()
+	}
+
+	return result, err
+}
+
+func Do(ctx context.Context, l *Limiter, fn func(context.Context) error) error {
+	_, err := Execute(ctx, l, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+
+	return err
+}
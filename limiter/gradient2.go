@@ -0,0 +1,180 @@
+package limiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+var _ Limit = (*Gradient2)(nil)
+
+// Gradient2 is a delay based limit that compares a short term round trip time (the latest
+// sample) against a long term exponential average. While the short term latency stays within
+// the tolerance of the long term average the limit keeps growing by the queue size, once it
+// diverges the limit is scaled down by the gradient
+//
+//	gradient = max(0.5, min(1, tolerance * longRtt / shortRtt))
+//	limit    = limit * gradient + queueSize
+//
+// A dropped call applies the minimum gradient, at most once for calls started after the previous drop.
+type Gradient2 struct {
+	mu sync.Mutex
+
+	limit        float64
+	minLimit     float64
+	maxLimit     float64
+	smoothing    float64
+	rttTolerance float64
+	queueSize    float64
+
+	longRtt *expAverage
+
+	lastDrop time.Time
+}
+
+type Gradient2Option func(*Gradient2)
+
+func WithGradient2InitialLimit(n int) Gradient2Option {
+	return func(g *Gradient2) {
+		g.limit = float64(n)
+	}
+}
+
+func WithGradient2MinLimit(n int) Gradient2Option {
+	return func(g *Gradient2) {
+		g.minLimit = float64(n)
+	}
+}
+
+func WithGradient2MaxLimit(n int) Gradient2Option {
+	return func(g *Gradient2) {
+		g.maxLimit = float64(n)
+	}
+}
+
+// WithGradient2Smoothing sets the weight in (0, 1] given to a newly computed limit
+func WithGradient2Smoothing(smoothing float64) Gradient2Option {
+	return func(g *Gradient2) {
+		g.smoothing = smoothing
+	}
+}
+
+// WithGradient2RTTTolerance sets how much the short term latency may exceed the long term
+// average before the limit is reduced, 1.5 tolerates a 50% increase
+func WithGradient2RTTTolerance(tolerance float64) Gradient2Option {
+	return func(g *Gradient2) {
+		g.rttTolerance = tolerance
+	}
+}
+
+// WithGradient2QueueSize sets the number of calls the limit grows by on every sample
+func WithGradient2QueueSize(n int) Gradient2Option {
+	return func(g *Gradient2) {
+		g.queueSize = float64(n)
+	}
+}
+
+// WithGradient2LongWindow sets the number of samples in the long term latency average
+func WithGradient2LongWindow(samples int) Gradient2Option {
+	return func(g *Gradient2) {
+		g.longRtt = newExpAverage(samples, 10)
+	}
+}
+
+func NewGradient2(opts ...Gradient2Option) *Gradient2 {
+	g := &Gradient2{
+		limit:        20,
+		minLimit:     1,
+		maxLimit:     200,
+		smoothing:    0.2,
+		rttTolerance: 1.5,
+		queueSize:    4,
+		longRtt:      newExpAverage(600, 10),
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	g.limit = clampLimit(g.limit, g.minLimit, g.maxLimit)
+
+	return g
+}
+
+func (g *Gradient2) EstimatedLimit() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return int(g.limit)
+}
+
+func (g *Gradient2) OnSample(startTime time.Time, rtt time.Duration, inflight int, didDrop bool) {
+	if rtt <= 0 {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if didDrop {
+		if startTime.Before(g.lastDrop) {
+			return
+		}
+
+		g.lastDrop = startTime.Add(rtt)
+		g.updateLimitUnsafe(0.5)
+		return
+	}
+
+	shortRtt := float64(rtt)
+	longRtt := g.longRtt.add(shortRtt)
+
+	// the long term average lags behind after a sustained latency increase, decay it
+	// so the limit can recover once the dependency is healthy again
+	if longRtt/shortRtt > 2 {
+		g.longRtt.scale(0.95)
+	}
+
+	if float64(inflight) < g.limit/2 {
+		return
+	}
+
+	g.updateLimitUnsafe(math.Max(0.5, math.Min(1, g.rttTolerance*longRtt/shortRtt)))
+}
+
+func (g *Gradient2) updateLimitUnsafe(gradient float64) {
+	newLimit := g.limit*gradient + g.queueSize
+	newLimit = g.limit*(1-g.smoothing) + newLimit*g.smoothing
+
+	g.limit = clampLimit(newLimit, g.minLimit, g.maxLimit)
+}
+
+// expAverage is an exponential moving average that uses a plain average during warmup
+type expAverage struct {
+	factor  float64
+	warmup  int
+	count   int
+	average float64
+}
+
+func newExpAverage(window, warmup int) *expAverage {
+	return &expAverage{
+		factor: 2.0 / float64(window+1),
+		warmup: warmup,
+	}
+}
+
+func (a *expAverage) add(value float64) float64 {
+	if a.count < a.warmup {
+		a.count++
+		a.average += (value - a.average) / float64(a.count)
+		return a.average
+	}
+
+	a.average = a.average*(1-a.factor) + value*a.factor
+	return a.average
+}
+
+func (a *expAverage) scale(factor float64) {
+	a.average *= factor
+}
@@ -0,0 +1,23 @@
+package limiter
+
+import (
+	"math"
+	"time"
+)
+
+// Limit is an algorithm that estimates the concurrency limit of a dependency from
+// the round trip time and the number of in-flight calls observed for each sample
+type Limit interface {
+	// EstimatedLimit returns the current concurrency limit
+	EstimatedLimit() int
+
+	// OnSample updates the limit with a completed call, inflight is the number of calls
+	// in flight when the call started and didDrop reports whether the call was dropped
+	// (e.g. timed out or rejected by the dependency because of load)
+	OnSample(startTime time.Time, rtt time.Duration, inflight int, didDrop bool)
+}
+
+// clampLimit bounds limit to [minLimit, maxLimit]
+func clampLimit(limit, minLimit, maxLimit float64) float64 {
+	return math.Max(minLimit, math.Min(maxLimit, limit))
+}
@@ -0,0 +1,166 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrLimitExceeded = errors.New("limiter: concurrency limit exceeded")
+
+func IsLimitExceededError(err error) bool {
+	return errors.Is(err, ErrLimitExceeded)
+}
+
+// Listener is returned for every acquired call and must be completed exactly once
+// with one of its methods, further calls are ignored
+type Listener interface {
+	// OnSuccess releases the call and feeds its round trip time to the limit
+	OnSuccess()
+
+	// OnDropped releases the call and tells the limit that it was dropped because of load,
+	// e.g. it timed out or the dependency rejected it
+	OnDropped()
+
+	// OnIgnore releases the call without feeding it to the limit, for calls whose
+	// latency says nothing about the dependency (e.g. failed validation)
+	OnIgnore()
+}
+
+type Config struct {
+	Limit Limit
+
+	// Now returns the current time, it can be replaced for deterministic tests
+	Now func() time.Time
+
+	// DropOnErrorPredicate decides whether an error returned from Execute means the call was dropped.
+	// Errors it does not match are ignored.
+	DropOnErrorPredicate func(error) bool
+}
+
+type Option func(*Config)
+
+func defaultConfig() Config {
+	return Config{
+		Limit:                NewAIMD(),
+		Now:                  time.Now,
+		DropOnErrorPredicate: isDeadlineExceeded,
+	}
+}
+
+func isDeadlineExceeded(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+func WithLimit(limit Limit) Option {
+	return func(c *Config) {
+		c.Limit = limit
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(c *Config) {
+		c.Now = now
+	}
+}
+
+func WithDropOnErrorPredicate(predicate func(error) bool) Option {
+	return func(c *Config) {
+		c.DropOnErrorPredicate = predicate
+	}
+}
+
+// Limiter rejects calls once the number of calls in flight reaches the limit estimated by its Limit
+type Limiter struct {
+	config Config
+
+	mu       sync.Mutex
+	inflight int
+}
+
+func New(opts ...Option) *Limiter {
+	config := defaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &Limiter{
+		config: config,
+	}
+}
+
+// Acquire reserves a slot for a call, it returns ErrLimitExceeded when the limit is reached
+func (l *Limiter) Acquire(ctx context.Context) (Listener, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inflight >= l.config.Limit.EstimatedLimit() {
+		return nil, ErrLimitExceeded
+	}
+
+	l.inflight++
+
+	return &listener{
+		limiter:   l,
+		startTime: l.config.Now(),
+		inflight:  l.inflight,
+	}, nil
+}
+
+// Inflight returns the number of calls currently in flight
+func (l *Limiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inflight
+}
+
+// Limit returns the current concurrency limit
+func (l *Limiter) Limit() int {
+	return l.config.Limit.EstimatedLimit()
+}
+
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+}
+
+type listener struct {
+	limiter   *Limiter
+	startTime time.Time
+	inflight  int
+	done      atomic.Bool
+}
+
+func (li *listener) complete(sample, didDrop bool) {
+	if !li.done.CompareAndSwap(false, true) {
+		return
+	}
+
+	li.limiter.release()
+
+	if sample {
+		rtt := li.limiter.config.Now().Sub(li.startTime)
+		li.limiter.config.Limit.OnSample(li.startTime, rtt, li.inflight, didDrop)
+	}
+}
+
+func (li *listener) OnSuccess() {
+	li.complete(true, false)
+}
+
+func (li *listener) OnDropped() {
+	li.complete(true, true)
+}
+
+func (li *listener) OnIgnore() {
+	li.complete(false, false)
+}
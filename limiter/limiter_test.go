@@ -0,0 +1,142 @@
+package limiter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/limiter"
+	"github.com/hugolhafner/dskit/limiter/limitertest"
+)
+
+type sample struct {
+	rtt      time.Duration
+	inflight int
+	didDrop  bool
+}
+
+type recordingLimit struct {
+	limit   int
+	samples []sample
+}
+
+func (r *recordingLimit) EstimatedLimit() int {
+	return r.limit
+}
+
+func (r *recordingLimit) OnSample(_ time.Time, rtt time.Duration, inflight int, didDrop bool) {
+	r.samples = append(r.samples, sample{rtt: rtt, inflight: inflight, didDrop: didDrop})
+}
+
+func TestLimiter_AcquireRespectsLimit(t *testing.T) {
+	clock := limitertest.NewClock(time.Unix(0, 0))
+	limit := &recordingLimit{limit: 2}
+	l := limiter.New(limiter.WithLimit(limit), limiter.WithClock(clock.Now))
+
+	first, err := l.Acquire(context.Background())
+	require.NoError(t, err)
+	second, err := l.Acquire(context.Background())
+	require.NoError(t, err)
+
+	_, err = l.Acquire(context.Background())
+	require.ErrorIs(t, err, limiter.ErrLimitExceeded)
+
+	clock.Advance(50 * time.Millisecond)
+	first.OnSuccess()
+	first.OnDropped() // completing twice is a no-op
+	second.OnIgnore()
+
+	require.Zero(t, l.Inflight())
+	require.Equal(t, []sample{{rtt: 50 * time.Millisecond, inflight: 1}}, limit.samples)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = l.Acquire(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestExecute_ClassifiesErrors(t *testing.T) {
+	limit := &recordingLimit{limit: 10}
+	l := limiter.New(limiter.WithLimit(limit))
+
+	require.NoError(t, limiter.Do(context.Background(), l, func(context.Context) error { return nil }))
+	require.Error(t, limiter.Do(context.Background(), l, func(context.Context) error {
+		return context.DeadlineExceeded
+	}))
+	require.Error(t, limiter.Do(context.Background(), l, func(context.Context) error {
+		return errors.New("bad request")
+	}))
+	require.Panics(t, func() {
+		_ = limiter.Do(context.Background(), l, func(context.Context) error { panic("boom") })
+	})
+
+	require.Len(t, limit.samples, 2)
+	require.False(t, limit.samples[0].didDrop)
+	require.True(t, limit.samples[1].didDrop)
+	require.Zero(t, l.Inflight())
+}
+
+// overloadSimulation offers far more load than the server can take, then cuts its capacity
+func overloadSimulation() limitertest.Simulation {
+	server := limitertest.Server{Capacity: 50, BaseLatency: 10 * time.Millisecond, Timeout: 30 * time.Millisecond}
+	degraded := server
+	degraded.Capacity = 10
+
+	return limitertest.Simulation{
+		Phases: []limitertest.Phase{
+			{Server: server, Rate: 10000, Duration: 10 * time.Second},
+			{Server: degraded, Rate: 10000, Duration: 10 * time.Second},
+		},
+		SampleInterval: time.Second,
+	}
+}
+
+func windowed(limit limiter.Limit) limiter.Limit {
+	return limiter.NewWindowed(limit, limiter.WithWindowDuration(20*time.Millisecond, time.Second))
+}
+
+func TestSimulation_LimitsConverge(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit func() limiter.Limit
+	}{
+		{name: "aimd", limit: func() limiter.Limit { return windowed(limiter.NewAIMD()) }},
+		{name: "vegas", limit: func() limiter.Limit { return windowed(limiter.NewVegas()) }},
+		{name: "gradient2", limit: func() limiter.Limit { return windowed(limiter.NewGradient2()) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace := overloadSimulation().Run(tt.limit())
+			require.Len(t, trace, 20)
+
+			// the server drops calls above 3x its capacity, limits must settle between capacity and that point
+			for _, s := range trace[5:10] {
+				require.GreaterOrEqual(t, s.Limit, 50, "at %v", s.Time)
+				require.LessOrEqual(t, s.Limit, 180, "at %v", s.Time)
+			}
+
+			for _, s := range trace[15:20] {
+				require.GreaterOrEqual(t, s.Limit, 10, "at %v", s.Time)
+				require.LessOrEqual(t, s.Limit, 40, "at %v", s.Time)
+			}
+
+			var accepted, dropped int
+			for _, s := range trace {
+				accepted += s.Accepted
+				dropped += s.Dropped
+			}
+			require.Less(t, float64(dropped)/float64(accepted), 0.1)
+		})
+	}
+}
+
+func TestSimulation_IsDeterministic(t *testing.T) {
+	first := overloadSimulation().Run(windowed(limiter.NewGradient2()))
+	second := overloadSimulation().Run(windowed(limiter.NewGradient2()))
+
+	require.Equal(t, first, second)
+}
@@ -0,0 +1,213 @@
+// Package limitertest provides a deterministic simulation harness for concurrency limits.
+package limitertest
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hugolhafner/dskit/limiter"
+)
+
+// Clock is a manually advanced clock
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Set moves the clock to t, it never moves backwards
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// Server models a dependency that serves Capacity calls concurrently at BaseLatency.
+// Calls beyond the capacity share the server, so latency grows linearly with the overload.
+// Calls slower than Timeout are dropped, a zero Timeout never drops.
+type Server struct {
+	Capacity    int
+	BaseLatency time.Duration
+	Timeout     time.Duration
+}
+
+// Latency returns the latency of a call that starts while inflight calls (including itself) are being served
+func (s Server) Latency(inflight int) time.Duration {
+	if inflight <= s.Capacity || s.Capacity <= 0 {
+		return s.BaseLatency
+	}
+
+	return time.Duration(float64(s.BaseLatency) * float64(inflight) / float64(s.Capacity))
+}
+
+func (s Server) dropped(latency time.Duration) bool {
+	return s.Timeout > 0 && latency > s.Timeout
+}
+
+// Phase offers calls at a constant Rate (calls per second) to Server for Duration
+type Phase struct {
+	Server   Server
+	Rate     float64
+	Duration time.Duration
+}
+
+// Sample is the trace of a single sampling interval
+type Sample struct {
+	// Time is the virtual time elapsed since the start of the simulation
+	Time time.Duration
+
+	// Limit and Inflight are observed at the end of the interval
+	Limit    int
+	Inflight int
+
+	Accepted int
+	Rejected int
+	Dropped  int
+}
+
+// Simulation drives a limiter through a sequence of phases in virtual time. Calls arrive at the
+// phase rate, are admitted or rejected by the limiter and complete after the server latency for
+// the concurrency at the time they started. No real time passes and no randomness is involved,
+// so the same inputs always produce the same trace.
+type Simulation struct {
+	Phases []Phase
+
+	// SampleInterval is the length of each Sample in the trace, defaults to 100ms
+	SampleInterval time.Duration
+}
+
+type completion struct {
+	at       time.Time
+	listener limiter.Listener
+	dropped  bool
+}
+
+type completionQueue []completion
+
+func (q completionQueue) Len() int           { return len(q) }
+func (q completionQueue) Less(i, j int) bool { return q[i].at.Before(q[j].at) }
+func (q completionQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *completionQueue) Push(x any)        { *q = append(*q, x.(completion)) }
+func (q *completionQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+type runner struct {
+	clock    *Clock
+	start    time.Time
+	limiter  *limiter.Limiter
+	pending  completionQueue
+	interval time.Duration
+
+	current Sample
+	trace   []Sample
+}
+
+// Run drives a limiter using limit through every phase and returns the sampled trace
+func (s Simulation) Run(limit limiter.Limit) []Sample {
+	interval := s.SampleInterval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+
+	clock := NewClock(time.Unix(0, 0))
+	r := &runner{
+		clock:    clock,
+		start:    clock.Now(),
+		limiter:  limiter.New(limiter.WithLimit(limit), limiter.WithClock(clock.Now)),
+		interval: interval,
+	}
+	r.current.Time = interval
+
+	phaseStart := r.start
+	for _, phase := range s.Phases {
+		r.runPhase(phase, phaseStart)
+		phaseStart = phaseStart.Add(phase.Duration)
+	}
+
+	r.completeUntil(phaseStart)
+	r.flushUntil(phaseStart)
+
+	return r.trace
+}
+
+func (r *runner) runPhase(phase Phase, phaseStart time.Time) {
+	if phase.Rate <= 0 {
+		return
+	}
+
+	gap := time.Duration(float64(time.Second) / phase.Rate)
+	end := phaseStart.Add(phase.Duration)
+
+	for at := phaseStart; at.Before(end); at = at.Add(gap) {
+		r.completeUntil(at)
+		r.flushUntil(at)
+		r.clock.Set(at)
+
+		li, err := r.limiter.Acquire(context.Background())
+		if err != nil {
+			r.current.Rejected++
+			continue
+		}
+
+		r.current.Accepted++
+		latency := phase.Server.Latency(r.limiter.Inflight())
+		heap.Push(&r.pending, completion{
+			at:       at.Add(latency),
+			listener: li,
+			dropped:  phase.Server.dropped(latency),
+		})
+	}
+}
+
+// completeUntil completes every pending call due at or before t in time order
+func (r *runner) completeUntil(t time.Time) {
+	for r.pending.Len() > 0 && !r.pending[0].at.After(t) {
+		c := heap.Pop(&r.pending).(completion)
+
+		r.flushUntil(c.at)
+		r.clock.Set(c.at)
+
+		if c.dropped {
+			r.current.Dropped++
+			c.listener.OnDropped()
+		} else {
+			c.listener.OnSuccess()
+		}
+	}
+}
+
+// flushUntil closes every sample interval that ends at or before t
+func (r *runner) flushUntil(t time.Time) {
+	for !r.start.Add(r.current.Time).After(t) {
+		r.current.Limit = r.limiter.Limit()
+		r.current.Inflight = r.limiter.Inflight()
+		r.trace = append(r.trace, r.current)
+		r.current = Sample{Time: r.current.Time + r.interval}
+	}
+}
@@ -0,0 +1,141 @@
+package limiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+var _ Limit = (*Vegas)(nil)
+
+// Vegas is a delay based limit derived from TCP Vegas. It tracks the minimum observed
+// round trip time as the no-load latency and estimates the queue size at the dependency as
+//
+//	queue = limit * (1 - rttNoLoad/rtt)
+//
+// The limit grows quickly while the queue is small, slowly while it is below alpha and
+// shrinks once it exceeds beta. The no-load latency is periodically re-probed so it can
+// follow the dependency when its baseline latency changes.
+type Vegas struct {
+	mu sync.Mutex
+
+	limit     float64
+	maxLimit  float64
+	smoothing float64
+
+	rttNoLoad time.Duration
+
+	probeMultiplier int
+	probeCount      int
+}
+
+type VegasOption func(*Vegas)
+
+func WithVegasInitialLimit(n int) VegasOption {
+	return func(v *Vegas) {
+		v.limit = float64(n)
+	}
+}
+
+func WithVegasMaxLimit(n int) VegasOption {
+	return func(v *Vegas) {
+		v.maxLimit = float64(n)
+	}
+}
+
+// WithVegasSmoothing sets the weight in (0, 1] given to a newly computed limit
+func WithVegasSmoothing(smoothing float64) VegasOption {
+	return func(v *Vegas) {
+		v.smoothing = smoothing
+	}
+}
+
+// WithVegasProbeMultiplier sets how often the no-load latency is reset, as a multiple of the current limit
+func WithVegasProbeMultiplier(multiplier int) VegasOption {
+	return func(v *Vegas) {
+		v.probeMultiplier = multiplier
+	}
+}
+
+func NewVegas(opts ...VegasOption) *Vegas {
+	v := &Vegas{
+		limit:           20,
+		maxLimit:        1000,
+		smoothing:       1.0,
+		probeMultiplier: 30,
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	v.limit = clampLimit(v.limit, 1, v.maxLimit)
+
+	return v
+}
+
+// log10Root returns max(1, floor(log10(limit))), which scales the thresholds with the limit
+func log10Root(limit float64) float64 {
+	return math.Max(1, math.Floor(math.Log10(limit)))
+}
+
+func (v *Vegas) EstimatedLimit() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return int(v.limit)
+}
+
+func (v *Vegas) OnSample(_ time.Time, rtt time.Duration, inflight int, didDrop bool) {
+	if rtt <= 0 {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.probeCount++
+	if v.probeMultiplier > 0 && float64(v.probeCount) >= float64(v.probeMultiplier)*v.limit {
+		v.probeCount = 0
+		v.rttNoLoad = rtt
+		return
+	}
+
+	if v.rttNoLoad == 0 || rtt < v.rttNoLoad {
+		v.rttNoLoad = rtt
+		return
+	}
+
+	v.updateLimitUnsafe(rtt, inflight, didDrop)
+}
+
+func (v *Vegas) updateLimitUnsafe(rtt time.Duration, inflight int, didDrop bool) {
+	queueSize := math.Ceil(v.limit * (1 - float64(v.rttNoLoad)/float64(rtt)))
+	logLimit := log10Root(v.limit)
+
+	var newLimit float64
+	switch {
+	case didDrop:
+		newLimit = v.limit - logLimit
+	case float64(inflight)*2 < v.limit:
+		// the caller is not using the limit, so there is nothing to learn from this sample
+		return
+	default:
+		alpha := 3 * logLimit
+		beta := 6 * logLimit
+
+		switch {
+		case queueSize <= logLimit:
+			newLimit = v.limit + beta
+		case queueSize < alpha:
+			newLimit = v.limit + logLimit
+		case queueSize > beta:
+			newLimit = v.limit - logLimit
+		default:
+			return
+		}
+	}
+
+	newLimit = clampLimit(newLimit, 1, v.maxLimit)
+	v.limit = (1-v.smoothing)*v.limit + v.smoothing*newLimit
+}
@@ -0,0 +1,101 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+var _ Limit = (*Windowed)(nil)
+
+// Windowed aggregates samples over a window and feeds a single sample per window to the
+// wrapped limit, with the average round trip time, the maximum in-flight count and whether
+// any call was dropped. Algorithms that adjust the limit on every sample grow proportionally
+// to throughput, wrapping them makes the rate of change independent of the request rate.
+type Windowed struct {
+	mu sync.Mutex
+
+	delegate   Limit
+	minWindow  time.Duration
+	maxWindow  time.Duration
+	minSamples int
+
+	windowStart time.Time
+	nextUpdate  time.Time
+
+	samples     int
+	rttSum      time.Duration
+	maxInflight int
+	didDrop     bool
+}
+
+type WindowedOption func(*Windowed)
+
+// WithWindowDuration bounds the window length, which is otherwise twice the average round trip time
+func WithWindowDuration(minWindow, maxWindow time.Duration) WindowedOption {
+	return func(w *Windowed) {
+		w.minWindow = minWindow
+		w.maxWindow = maxWindow
+	}
+}
+
+// WithWindowMinSamples sets the number of samples required before a window is closed
+func WithWindowMinSamples(n int) WindowedOption {
+	return func(w *Windowed) {
+		w.minSamples = n
+	}
+}
+
+func NewWindowed(delegate Limit, opts ...WindowedOption) *Windowed {
+	w := &Windowed{
+		delegate:   delegate,
+		minWindow:  time.Second,
+		maxWindow:  time.Second,
+		minSamples: 10,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+func (w *Windowed) EstimatedLimit() int {
+	return w.delegate.EstimatedLimit()
+}
+
+func (w *Windowed) OnSample(startTime time.Time, rtt time.Duration, inflight int, didDrop bool) {
+	w.mu.Lock()
+
+	endTime := startTime.Add(rtt)
+	if w.nextUpdate.IsZero() {
+		w.windowStart = startTime
+		w.nextUpdate = endTime.Add(w.minWindow)
+	}
+
+	w.samples++
+	w.rttSum += rtt
+	w.maxInflight = max(w.maxInflight, inflight)
+	w.didDrop = w.didDrop || didDrop
+
+	if endTime.Before(w.nextUpdate) || w.samples < w.minSamples {
+		w.mu.Unlock()
+		return
+	}
+
+	windowStart := w.windowStart
+	avgRtt := w.rttSum / time.Duration(w.samples)
+	maxInflight := w.maxInflight
+	dropped := w.didDrop
+
+	w.windowStart = endTime
+	w.nextUpdate = endTime.Add(min(max(2*avgRtt, w.minWindow), w.maxWindow))
+	w.samples = 0
+	w.rttSum = 0
+	w.maxInflight = 0
+	w.didDrop = false
+
+	w.mu.Unlock()
+
+	w.delegate.OnSample(windowStart, avgRtt, maxInflight, dropped)
+}
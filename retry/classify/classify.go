@@ -0,0 +1,87 @@
+// Package classify provides composable classifiers that decide whether an error is worth
+// retrying, for use with retry.WithClassifier through RetryClassifier or with
+// retry.WithRetryOnErrorPredicate through Predicate.
+package classify
+
+import (
+	"github.com/hugolhafner/dskit/retry"
+)
+
+// Verdict is the decision of a classifier about an error
+type Verdict int
+
+const (
+	// Unknown leaves the decision to the next classifier
+	Unknown Verdict = iota
+	Retry
+	DoNotRetry
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Unknown:
+		return "unknown"
+	case Retry:
+		return "retry"
+	case DoNotRetry:
+		return "do_not_retry"
+	default:
+		return "invalid"
+	}
+}
+
+// Classifier decides whether err is worth retrying, returning Unknown for errors it does not recognize
+type Classifier interface {
+	Classify(err error) Verdict
+}
+
+var _ Classifier = Func(nil)
+
+// Func adapts a function to a Classifier
+type Func func(err error) Verdict
+
+func (f Func) Classify(err error) Verdict {
+	return f(err)
+}
+
+// Chain returns the verdict of the first classifier that recognizes the error
+func Chain(classifiers ...Classifier) Classifier {
+	return Func(func(err error) Verdict {
+		for _, c := range classifiers {
+			if v := c.Classify(err); v != Unknown {
+				return v
+			}
+		}
+
+		return Unknown
+	})
+}
+
+// Fallback returns verdict for every error, as the last classifier of a chain
+func Fallback(verdict Verdict) Classifier {
+	return Func(func(error) Verdict {
+		return verdict
+	})
+}
+
+// Predicate chains the classifiers into a predicate for retry.WithRetryOnErrorPredicate,
+// errors none of them recognize are not retried unless the chain ends with Fallback(Retry)
+func Predicate(classifiers ...Classifier) func(error) bool {
+	chain := Chain(classifiers...)
+	return func(err error) bool {
+		return err != nil && chain.Classify(err) == Retry
+	}
+}
+
+// RetryClassifier chains the classifiers into a retry.Classifier for retry.WithClassifier, so
+// attempts record that a classifier decided them. Errors none of them recognize are not retried.
+func RetryClassifier(classifiers ...Classifier) retry.Classifier {
+	chain := Chain(classifiers...)
+	return retry.ClassifierFunc(func(err error) retry.Decision {
+		if chain.Classify(err) == Retry {
+			return retry.RetryDecision(retry.DecisionReasonClassifier)
+		}
+
+		return retry.StopDecision(retry.DecisionReasonClassifier)
+	})
+}
@@ -0,0 +1,158 @@
+package classify_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/retry"
+	"github.com/hugolhafner/dskit/retry/classify"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string   { return fmt.Sprintf("unexpected status %d", e.code) }
+func (e *statusError) StatusCode() int { return e.code }
+
+func TestNetwork(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want classify.Verdict
+	}{
+		{
+			name: "connection reset",
+			err:  &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)},
+			want: classify.Retry,
+		},
+		{
+			name: "connection refused",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+			want: classify.Retry,
+		},
+		{
+			name: "unexpected eof",
+			err:  fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF),
+			want: classify.Retry,
+		},
+		{
+			name: "timeout",
+			err:  &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}},
+			want: classify.Retry,
+		},
+		{
+			name: "dns not found",
+			err:  &net.DNSError{Err: "no such host", Name: "missing.invalid", IsNotFound: true},
+			want: classify.DoNotRetry,
+		},
+		{
+			name: "dns timeout",
+			err:  &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true},
+			want: classify.Retry,
+		},
+		{
+			name: "tls unknown authority",
+			err:  &net.OpError{Op: "remote error", Err: x509.UnknownAuthorityError{}},
+			want: classify.DoNotRetry,
+		},
+		{
+			name: "unrelated",
+			err:  errors.New("boom"),
+			want: classify.Unknown,
+		},
+		{
+			name: "context canceled",
+			err:  context.Canceled,
+			want: classify.Unknown,
+		},
+	}
+
+	network := classify.Network()
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				require.Equal(t, tt.want, network.Classify(tt.err))
+			},
+		)
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	status := classify.HTTPStatus()
+
+	require.Equal(t, classify.Retry, status.Classify(&statusError{code: http.StatusServiceUnavailable}))
+	require.Equal(t, classify.Retry, status.Classify(fmt.Errorf("call: %w", &statusError{code: http.StatusTooManyRequests})))
+	require.Equal(t, classify.DoNotRetry, status.Classify(&statusError{code: http.StatusBadRequest}))
+	require.Equal(t, classify.Unknown, status.Classify(errors.New("boom")))
+}
+
+func TestStatusCodes(t *testing.T) {
+	type code string
+
+	type codeError struct {
+		error
+		code code
+	}
+
+	codeOf := func(err error) (code, bool) {
+		var ce codeError
+		if !errors.As(err, &ce) {
+			return "", false
+		}
+		return ce.code, true
+	}
+
+	c := classify.StatusCodes(codeOf, "UNAVAILABLE", "RESOURCE_EXHAUSTED")
+	require.Equal(t, classify.Retry, c.Classify(codeError{errors.New("unavailable"), "UNAVAILABLE"}))
+	require.Equal(t, classify.DoNotRetry, c.Classify(codeError{errors.New("not found"), "NOT_FOUND"}))
+	require.Equal(t, classify.Unknown, c.Classify(errors.New("boom")))
+}
+
+func TestPredicate(t *testing.T) {
+	onlyKnown := classify.Predicate(classify.Network(), classify.HTTPStatus())
+	require.True(t, onlyKnown(io.ErrUnexpectedEOF))
+	require.False(t, onlyKnown(&statusError{code: http.StatusNotFound}))
+	require.False(t, onlyKnown(errors.New("boom")))
+	require.False(t, onlyKnown(nil))
+
+	retryUnknown := classify.Predicate(classify.Network(), classify.Fallback(classify.Retry))
+	require.True(t, retryUnknown(errors.New("boom")))
+	require.False(t, retryUnknown(&net.DNSError{IsNotFound: true}))
+
+	policy, err := retry.NewPolicy("classified", retry.WithRetryOnErrorPredicate(onlyKnown))
+	require.NoError(t, err)
+	require.True(t, policy.ShouldRetryError(&statusError{code: http.StatusBadGateway}))
+	require.False(t, policy.ShouldRetryError(&statusError{code: http.StatusForbidden}))
+}
+
+func TestRetryClassifier(t *testing.T) {
+	policy, err := retry.NewPolicy(
+		"classified", retry.WithClassifier(classify.RetryClassifier(classify.Network(), classify.HTTPStatus())),
+	)
+	require.NoError(t, err)
+
+	require.Equal(
+		t, retry.RetryDecision(retry.DecisionReasonClassifier), policy.ClassifyError(syscall.ECONNRESET),
+	)
+	require.Equal(
+		t, retry.StopDecision(retry.DecisionReasonClassifier),
+		policy.ClassifyError(&statusError{code: http.StatusUnauthorized}),
+	)
+}
@@ -0,0 +1,103 @@
+package classify
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// Network classifies transport errors: TLS, DNS and syscall errors as decided by their own
+// classifiers, then connections closed mid-call, timeouts and failed dials as retryable
+func Network() Classifier {
+	return Chain(TLS(), DNS(), Syscall(), Func(classifyNet))
+}
+
+// TLS does not retry certificate and handshake errors, which repeat on every attempt
+func TLS() Classifier {
+	return Func(func(err error) Verdict {
+		var (
+			verificationErr *tls.CertificateVerificationError
+			alertErr        tls.AlertError
+			recordHeaderErr tls.RecordHeaderError
+			unknownAuthErr  x509.UnknownAuthorityError
+			hostnameErr     x509.HostnameError
+			invalidErr      x509.CertificateInvalidError
+		)
+
+		switch {
+		case errors.As(err, &verificationErr),
+			errors.As(err, &alertErr),
+			errors.As(err, &recordHeaderErr),
+			errors.As(err, &unknownAuthErr),
+			errors.As(err, &hostnameErr),
+			errors.As(err, &invalidErr):
+			return DoNotRetry
+		default:
+			return Unknown
+		}
+	})
+}
+
+// DNS does not retry names that do not exist and retries any other lookup failure,
+// e.g. timeouts or a misbehaving server
+func DNS() Classifier {
+	return Func(func(err error) Verdict {
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) {
+			return Unknown
+		}
+
+		if dnsErr.IsNotFound {
+			return DoNotRetry
+		}
+
+		return Retry
+	})
+}
+
+// retryableErrnos are failures of a connection or the route to the peer, which another attempt
+// over a new connection may not run into
+var retryableErrnos = []syscall.Errno{
+	syscall.ECONNRESET,
+	syscall.ECONNREFUSED,
+	syscall.ECONNABORTED,
+	syscall.EPIPE,
+	syscall.ETIMEDOUT,
+	syscall.EHOSTUNREACH,
+	syscall.ENETUNREACH,
+}
+
+// Syscall retries connection resets, refusals, aborts, broken pipes, timeouts and unreachable hosts
+func Syscall() Classifier {
+	return Func(func(err error) Verdict {
+		for _, errno := range retryableErrnos {
+			if errors.Is(err, errno) {
+				return Retry
+			}
+		}
+
+		return Unknown
+	})
+}
+
+func classifyNet(err error) Verdict {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return Retry
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return Retry
+	}
+
+	// nothing was sent when dialing failed, so another attempt is always safe
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return Retry
+	}
+
+	return Unknown
+}
@@ -0,0 +1,76 @@
+package classify
+
+import (
+	"errors"
+	"net/http"
+)
+
+var _ Classifier = (*StatusCodeClassifier[int])(nil)
+
+// StatusCodeClassifier classifies errors by a status code of type C they carry, e.g. an HTTP
+// status or a gRPC code. Errors with a code in Retryable are retried, errors with any other
+// code are not, and errors without a code are left to the next classifier.
+type StatusCodeClassifier[C comparable] struct {
+	// Code extracts the status code of err, ok is false when err carries none
+	Code      func(err error) (code C, ok bool)
+	Retryable map[C]struct{}
+}
+
+// StatusCodes retries errors whose code is one of retryable. gRPC codes plug in with a function
+// extracting the code from the status of the error:
+//
+//	classify.StatusCodes(func(err error) (codes.Code, bool) {
+//		s, ok := status.FromError(err)
+//		return s.Code(), ok
+//	}, codes.Unavailable, codes.ResourceExhausted)
+func StatusCodes[C comparable](code func(err error) (C, bool), retryable ...C) *StatusCodeClassifier[C] {
+	c := &StatusCodeClassifier[C]{
+		Code:      code,
+		Retryable: make(map[C]struct{}, len(retryable)),
+	}
+	for _, r := range retryable {
+		c.Retryable[r] = struct{}{}
+	}
+
+	return c
+}
+
+func (c *StatusCodeClassifier[C]) Classify(err error) Verdict {
+	code, ok := c.Code(err)
+	if !ok {
+		return Unknown
+	}
+
+	if _, ok := c.Retryable[code]; ok {
+		return Retry
+	}
+
+	return DoNotRetry
+}
+
+// RetryableHTTPStatusCodes are the statuses HTTPStatus retries: timeouts, rate limits and
+// gateway or availability errors that are expected to be temporary
+var RetryableHTTPStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooEarly,
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// HTTPStatusCode extracts the status code of an error in the chain of err with a StatusCode() int method
+func HTTPStatusCode(err error) (int, bool) {
+	var coder interface{ StatusCode() int }
+	if !errors.As(err, &coder) {
+		return 0, false
+	}
+
+	return coder.StatusCode(), true
+}
+
+// HTTPStatus retries errors with one of the RetryableHTTPStatusCodes and no other status code,
+// errors carry their status through a StatusCode() int method
+func HTTPStatus() *StatusCodeClassifier[int] {
+	return StatusCodes(HTTPStatusCode, RetryableHTTPStatusCodes...)
+}
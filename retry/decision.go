@@ -0,0 +1,126 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DecisionReason explains why a failed attempt was or was not retried
+type DecisionReason string
+
+const (
+	// DecisionReasonRetryable is an error retried because no list or predicate excludes it
+	DecisionReasonRetryable DecisionReason = "retryable"
+
+	// DecisionReasonAllowlist is an error retried because it matched the retry errors
+	DecisionReasonAllowlist DecisionReason = "allowlist"
+
+	// DecisionReasonIgnored is an error not retried because it matched the ignored errors
+	DecisionReasonIgnored DecisionReason = "ignored"
+
+	// DecisionReasonNotInAllowlist is an error not retried because it matched none of the retry errors
+	DecisionReasonNotInAllowlist DecisionReason = "not_in_allowlist"
+
+	// DecisionReasonPredicate is an error decided by the error predicate
+	DecisionReasonPredicate DecisionReason = "predicate"
+
+	// DecisionReasonResult is a result retried because of the result predicate
+	DecisionReasonResult DecisionReason = "result"
+
+	// DecisionReasonClassifier is an error decided by the Classifier of the policy
+	DecisionReasonClassifier DecisionReason = "classifier"
+
+	// DecisionReasonBudget is a retryable attempt not retried because it was the last one permitted
+	DecisionReasonBudget DecisionReason = "budget"
+
+	// DecisionReasonDeadline is a retryable attempt not retried because the context deadline
+	// passes before the next attempt could start, the retry fails with ErrDeadlineBeforeRetry
+	DecisionReasonDeadline DecisionReason = "deadline"
+)
+
+// Decision is whether a failed attempt is retried and why, successful attempts have a zero Decision
+type Decision struct {
+	Retry  bool
+	Reason DecisionReason
+
+	// Delay replaces the backoff before the next attempt when positive, e.g. from a Retry-After header
+	Delay time.Duration
+}
+
+// RetryDecision returns a decision to retry for reason
+func RetryDecision(reason DecisionReason) Decision {
+	return Decision{Retry: true, Reason: reason}
+}
+
+// StopDecision returns a decision not to retry for reason
+func StopDecision(reason DecisionReason) Decision {
+	return Decision{Retry: false, Reason: reason}
+}
+
+func (d Decision) String() string {
+	if d.Reason == "" {
+		return "none"
+	}
+
+	action := "stop"
+	if d.Retry {
+		action = "retry"
+	}
+
+	if d.Delay > 0 {
+		return fmt.Sprintf("%s (%s, delay %v)", action, d.Reason, d.Delay)
+	}
+
+	return fmt.Sprintf("%s (%s)", action, d.Reason)
+}
+
+// Classifier decides whether an attempt that failed with err is retried. The policy still stops
+// once the attempts are exhausted or the context deadline passes, whatever the decision.
+type Classifier interface {
+	Classify(err error) Decision
+}
+
+var _ Classifier = ClassifierFunc(nil)
+
+// ClassifierFunc adapts a function to a Classifier
+type ClassifierFunc func(err error) Decision
+
+func (f ClassifierFunc) Classify(err error) Decision {
+	return f(err)
+}
+
+// ClassifyError decides whether an attempt that failed with err is retried. The Classifier takes
+// precedence, followed by the error predicate, then the ignored errors and the retry errors.
+func (p *Policy) ClassifyError(err error) Decision {
+	if err == nil {
+		return Decision{}
+	}
+
+	if p.classifier != nil {
+		return p.classifier.Classify(err)
+	}
+
+	if p.retryOnErrorPredicate != nil {
+		return Decision{Retry: p.retryOnErrorPredicate(err), Reason: DecisionReasonPredicate}
+	}
+
+	for _, ignoreErr := range p.ignoreErrors {
+		if errors.Is(err, ignoreErr) {
+			return StopDecision(DecisionReasonIgnored)
+		}
+	}
+
+	// If allowlist is defined, error must match
+	if len(p.retryErrors) > 0 {
+		for _, retryErr := range p.retryErrors {
+			if errors.Is(err, retryErr) {
+				return RetryDecision(DecisionReasonAllowlist)
+			}
+		}
+
+		return StopDecision(DecisionReasonNotInAllowlist)
+	}
+
+	return RetryDecision(DecisionReasonRetryable)
+}
@@ -0,0 +1,169 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/backoff"
+	"github.com/hugolhafner/dskit/retry"
+)
+
+var (
+	errTransient = errors.New("transient")
+	errPermanent = errors.New("permanent")
+)
+
+func TestPolicy_ClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []retry.Option
+		err  error
+		want retry.Decision
+	}{
+		{
+			name: "default",
+			err:  errTransient,
+			want: retry.RetryDecision(retry.DecisionReasonRetryable),
+		},
+		{
+			name: "ignored",
+			opts: []retry.Option{retry.WithIgnoreErrors(errPermanent)},
+			err:  errPermanent,
+			want: retry.StopDecision(retry.DecisionReasonIgnored),
+		},
+		{
+			name: "allowlist",
+			opts: []retry.Option{retry.WithRetryErrors(errTransient)},
+			err:  errTransient,
+			want: retry.RetryDecision(retry.DecisionReasonAllowlist),
+		},
+		{
+			name: "not in allowlist",
+			opts: []retry.Option{retry.WithRetryErrors(errTransient)},
+			err:  errPermanent,
+			want: retry.StopDecision(retry.DecisionReasonNotInAllowlist),
+		},
+		{
+			name: "predicate",
+			opts: []retry.Option{
+				retry.WithIgnoreErrors(errTransient),
+				retry.WithRetryOnErrorPredicate(func(err error) bool { return errors.Is(err, errTransient) }),
+			},
+			err:  errTransient,
+			want: retry.RetryDecision(retry.DecisionReasonPredicate),
+		},
+		{
+			name: "classifier",
+			opts: []retry.Option{
+				retry.WithRetryOnErrorPredicate(func(error) bool { return false }),
+				retry.WithClassifier(
+					retry.ClassifierFunc(func(error) retry.Decision {
+						return retry.Decision{Retry: true, Reason: retry.DecisionReasonClassifier, Delay: time.Second}
+					}),
+				),
+			},
+			err:  errTransient,
+			want: retry.Decision{Retry: true, Reason: retry.DecisionReasonClassifier, Delay: time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				policy := retry.MustNewPolicy("classify", tt.opts...)
+				require.Equal(t, tt.want, policy.ClassifyError(tt.err))
+				require.Equal(t, tt.want.Retry, policy.ShouldRetryError(tt.err))
+			},
+		)
+	}
+}
+
+func TestExecute_RecordsDecisions(t *testing.T) {
+	policy := retry.MustNewPolicy(
+		"decisions",
+		retry.WithMetrics(&retry.NoopMetrics{}),
+		retry.WithMaxAttempts(2),
+		retry.WithBackoff(backoff.NewFixed(time.Millisecond)),
+	)
+
+	err := retry.Do(context.Background(), policy, func(context.Context) error { return errTransient })
+
+	retryErr, ok := retry.AsRetryError(err)
+	require.True(t, ok)
+	require.Len(t, retryErr.Attempts, 2)
+	require.Equal(t, retry.RetryDecision(retry.DecisionReasonRetryable), retryErr.Attempts[0].Decision)
+
+	// the last attempt was retryable but no attempts were left
+	require.True(t, retryErr.Attempts[1].Retryable)
+	require.Equal(t, retry.StopDecision(retry.DecisionReasonBudget), retryErr.Attempts[1].Decision)
+	require.Contains(t, retryErr.Verbose(), "stop (budget)")
+}
+
+func TestExecute_DecisionDelayOverridesBackoff(t *testing.T) {
+	policy := retry.MustNewPolicy(
+		"delay",
+		retry.WithMetrics(&retry.NoopMetrics{}),
+		retry.WithBackoff(backoff.NewFixed(time.Hour)),
+		retry.WithClassifier(
+			retry.ClassifierFunc(func(error) retry.Decision {
+				return retry.Decision{Retry: true, Reason: retry.DecisionReasonClassifier, Delay: time.Millisecond}
+			}),
+		),
+	)
+
+	calls := 0
+	err := retry.Do(context.Background(), policy, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+}
+
+func TestExecute_StopsBeforeDeadline(t *testing.T) {
+	policy := retry.MustNewPolicy(
+		"deadline",
+		retry.WithMetrics(&retry.NoopMetrics{}),
+		retry.WithBackoff(backoff.NewFixed(time.Hour)),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	err := retry.Do(ctx, policy, func(context.Context) error { return errTransient })
+	require.Less(t, time.Since(start), time.Second)
+
+	// the deadline has not passed yet, so the error does not claim it did
+	require.ErrorIs(t, err, retry.ErrDeadlineBeforeRetry)
+	require.NotErrorIs(t, err, context.DeadlineExceeded)
+	require.NoError(t, ctx.Err())
+
+	retryErr, ok := retry.AsRetryError(err)
+	require.True(t, ok)
+	require.Len(t, retryErr.Attempts, 1)
+	require.Equal(t, retry.StopDecision(retry.DecisionReasonDeadline), retryErr.Attempts[0].Decision)
+}
+
+func TestExecute_NonRetryableDecision(t *testing.T) {
+	policy := retry.MustNewPolicy(
+		"ignored",
+		retry.WithMetrics(&retry.NoopMetrics{}),
+		retry.WithIgnoreErrors(errPermanent),
+	)
+
+	err := retry.Do(context.Background(), policy, func(context.Context) error { return errPermanent })
+
+	retryErr, ok := retry.AsRetryError(err)
+	require.True(t, ok)
+	require.Len(t, retryErr.Attempts, 1)
+	require.False(t, retryErr.Attempts[0].Retryable)
+	require.Equal(t, retry.StopDecision(retry.DecisionReasonIgnored), retryErr.Attempts[0].Decision)
+}
@@ -10,6 +10,11 @@ import (
 // ErrResultPredicateRetry is returned when the result predicate triggers a retry
 var ErrResultPredicateRetry = errors.New("result predicate triggered retry")
 
+// ErrDeadlineBeforeRetry is the TerminationError of a retry stopped because the next attempt would
+// only start after the context deadline. The context has not ended yet, so it does not match
+// context.DeadlineExceeded.
+var ErrDeadlineBeforeRetry = errors.New("next retry would start after the context deadline")
+
 // RetryError contains the complete retry history
 type RetryError struct {
 	Attempts         []Attempt
@@ -50,6 +55,11 @@ func (e *RetryError) All() []error {
 	return errs
 }
 
+// Joined returns e as a JoinedRetryError, so errors.Is and errors.As match the error of any attempt
+func (e *RetryError) Joined() *JoinedRetryError {
+	return &JoinedRetryError{RetryError: e}
+}
+
 // Verbose returns a full retry history error string
 func (e *RetryError) Verbose() string {
 	var sb strings.Builder
@@ -57,16 +67,48 @@ func (e *RetryError) Verbose() string {
 
 	for _, a := range e.Attempts {
 		sb.WriteString(fmt.Sprintf(
-			"  attempt %d [%s] (took %v): %v\n",
+			"  attempt %d [%s] (took %v): %v, %s\n",
 			a.Number,
 			a.Timestamp.Format(time.RFC3339),
 			a.Duration,
 			a.Error,
+			a.Decision,
 		))
 	}
 	return sb.String()
 }
 
+// JoinedRetryError is a RetryError that unwraps like errors.Join, to the termination error followed
+// by the error of every attempt rather than only the last one. It is returned by policies created
+// with WithJoinedErrors and is still found by AsRetryError.
+type JoinedRetryError struct {
+	*RetryError
+}
+
+// Unwrap returns the termination error, if any, followed by the errors of all attempts
+func (e *JoinedRetryError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Attempts)+1)
+	if e.TerminationError != nil {
+		errs = append(errs, e.TerminationError)
+	}
+
+	for _, a := range e.Attempts {
+		if a.Error != nil {
+			errs = append(errs, a.Error)
+		}
+	}
+	return errs
+}
+
+// As sets a *RetryError target to the wrapped RetryError, unwrapping by Unwrap otherwise
+func (e *JoinedRetryError) As(target any) bool {
+	if t, ok := target.(**RetryError); ok {
+		*t = e.RetryError
+		return true
+	}
+	return false
+}
+
 // AsRetryError checks if the given error is a retry RetryError
 func AsRetryError(err error) (*RetryError, bool) {
 	var e *RetryError
@@ -0,0 +1,183 @@
+package retry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+var (
+	_ json.Marshaler = (*RetryError)(nil)
+	_ json.Marshaler = Attempt{}
+	_ json.Marshaler = Outcome{}
+
+	_ slog.LogValuer = (*RetryError)(nil)
+	_ slog.LogValuer = Attempt{}
+	_ slog.LogValuer = Outcome{}
+)
+
+// errorJSON renders an error as its message and dynamic type, so errors without exported
+// fields still serialize to something useful
+type errorJSON struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+func newErrorJSON(err error) *errorJSON {
+	if err == nil {
+		return nil
+	}
+
+	return &errorJSON{Message: err.Error(), Type: fmt.Sprintf("%T", err)}
+}
+
+func errorLogValue(err error) slog.Value {
+	return slog.GroupValue(
+		slog.String("message", err.Error()),
+		slog.String("type", fmt.Sprintf("%T", err)),
+	)
+}
+
+type decisionJSON struct {
+	Retry  bool           `json:"retry"`
+	Reason DecisionReason `json:"reason"`
+	Delay  string         `json:"delay,omitempty"`
+}
+
+type attemptJSON struct {
+	Policy        string               `json:"policy"`
+	Number        int                  `json:"number"`
+	Timestamp     time.Time            `json:"timestamp"`
+	Duration      string               `json:"duration"`
+	Status        AttemptStatus        `json:"status"`
+	FailureReason AttemptFailureReason `json:"failure_reason,omitempty"`
+	Error         *errorJSON           `json:"error,omitempty"`
+	Retryable     bool                 `json:"retryable"`
+	Decision      *decisionJSON        `json:"decision,omitempty"`
+}
+
+// MarshalJSON renders the error as message and type and the durations as strings, e.g. "1.5s"
+func (a Attempt) MarshalJSON() ([]byte, error) {
+	v := attemptJSON{
+		Policy:        a.PolicyName,
+		Number:        a.Number,
+		Timestamp:     a.Timestamp,
+		Duration:      a.Duration.String(),
+		Status:        a.Status,
+		FailureReason: a.FailureReason,
+		Error:         newErrorJSON(a.Error),
+		Retryable:     a.Retryable,
+	}
+
+	if a.Decision.Reason != "" {
+		v.Decision = &decisionJSON{Retry: a.Decision.Retry, Reason: a.Decision.Reason}
+		if a.Decision.Delay > 0 {
+			v.Decision.Delay = a.Decision.Delay.String()
+		}
+	}
+
+	return json.Marshal(v)
+}
+
+func (a Attempt) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("policy", a.PolicyName),
+		slog.Int("number", a.Number),
+		slog.Time("timestamp", a.Timestamp),
+		slog.String("duration", a.Duration.String()),
+		slog.String("status", string(a.Status)),
+	}
+
+	if a.FailureReason != "" {
+		attrs = append(attrs, slog.String("failure_reason", string(a.FailureReason)))
+	}
+
+	if a.Error != nil {
+		attrs = append(attrs, slog.Attr{Key: "error", Value: errorLogValue(a.Error)})
+	}
+
+	if !a.IsSuccess() {
+		attrs = append(attrs, slog.Bool("retryable", a.Retryable), slog.String("decision", a.Decision.String()))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+type outcomeJSON struct {
+	Policy        string               `json:"policy"`
+	TotalAttempts int                  `json:"total_attempts"`
+	TotalDuration string               `json:"total_duration"`
+	Status        OutcomeStatus        `json:"status"`
+	FailureReason OutcomeFailureReason `json:"failure_reason,omitempty"`
+}
+
+// MarshalJSON renders the total duration as a string, e.g. "1.5s"
+func (o Outcome) MarshalJSON() ([]byte, error) {
+	return json.Marshal(
+		outcomeJSON{
+			Policy:        o.PolicyName,
+			TotalAttempts: o.TotalAttempts,
+			TotalDuration: o.TotalDuration.String(),
+			Status:        o.Status,
+			FailureReason: o.FailureReason,
+		},
+	)
+}
+
+func (o Outcome) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("policy", o.PolicyName),
+		slog.Int("total_attempts", o.TotalAttempts),
+		slog.String("total_duration", o.TotalDuration.String()),
+		slog.String("status", string(o.Status)),
+	}
+
+	if o.FailureReason != "" {
+		attrs = append(attrs, slog.String("failure_reason", string(o.FailureReason)))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+type retryErrorJSON struct {
+	Message          string     `json:"message"`
+	Attempts         []Attempt  `json:"attempts"`
+	TerminationError *errorJSON `json:"termination_error,omitempty"`
+}
+
+// MarshalJSON renders the message and every attempt, see Attempt.MarshalJSON
+func (e *RetryError) MarshalJSON() ([]byte, error) {
+	attempts := e.Attempts
+	if attempts == nil {
+		attempts = []Attempt{}
+	}
+
+	return json.Marshal(
+		retryErrorJSON{
+			Message:          e.Error(),
+			Attempts:         attempts,
+			TerminationError: newErrorJSON(e.TerminationError),
+		},
+	)
+}
+
+// LogValue groups the attempts by their number, e.g. attempts.1.error.message with the text handler
+func (e *RetryError) LogValue() slog.Value {
+	attempts := make([]slog.Attr, 0, len(e.Attempts))
+	for _, a := range e.Attempts {
+		attempts = append(attempts, slog.Attr{Key: strconv.Itoa(a.Number), Value: a.LogValue()})
+	}
+
+	attrs := []slog.Attr{
+		slog.String("message", e.Error()),
+		slog.Attr{Key: "attempts", Value: slog.GroupValue(attempts...)},
+	}
+
+	if e.TerminationError != nil {
+		attrs = append(attrs, slog.Attr{Key: "termination_error", Value: errorLogValue(e.TerminationError)})
+	}
+
+	return slog.GroupValue(attrs...)
+}
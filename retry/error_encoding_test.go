@@ -0,0 +1,181 @@
+package retry_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/backoff"
+	"github.com/hugolhafner/dskit/retry"
+)
+
+func encodingRetryError() *retry.RetryError {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	return &retry.RetryError{
+		Attempts: []retry.Attempt{
+			{
+				PolicyName:    "orders",
+				Number:        1,
+				Timestamp:     baseTime,
+				Duration:      1500 * time.Millisecond,
+				Status:        retry.AttemptStatusError,
+				FailureReason: retry.AttemptFailureReasonError,
+				Error:         errTransient,
+				Retryable:     true,
+				Decision:      retry.Decision{Retry: true, Reason: retry.DecisionReasonClassifier, Delay: time.Second},
+			},
+			{
+				PolicyName:    "orders",
+				Number:        2,
+				Timestamp:     baseTime.Add(3 * time.Second),
+				Duration:      time.Second,
+				Status:        retry.AttemptStatusError,
+				FailureReason: retry.AttemptFailureReasonError,
+				Error:         errPermanent,
+				Decision:      retry.StopDecision(retry.DecisionReasonIgnored),
+			},
+		},
+		TerminationError: context.DeadlineExceeded,
+	}
+}
+
+func TestRetryError_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(encodingRetryError())
+	require.NoError(t, err)
+
+	require.JSONEq(
+		t, `{
+			"message": "retry failed after 2 attempt(s): permanent",
+			"attempts": [
+				{
+					"policy": "orders",
+					"number": 1,
+					"timestamp": "2024-01-01T00:00:00Z",
+					"duration": "1.5s",
+					"status": "error",
+					"failure_reason": "error",
+					"error": {"message": "transient", "type": "*errors.errorString"},
+					"retryable": true,
+					"decision": {"retry": true, "reason": "classifier", "delay": "1s"}
+				},
+				{
+					"policy": "orders",
+					"number": 2,
+					"timestamp": "2024-01-01T00:00:03Z",
+					"duration": "1s",
+					"status": "error",
+					"failure_reason": "error",
+					"error": {"message": "permanent", "type": "*errors.errorString"},
+					"retryable": false,
+					"decision": {"retry": false, "reason": "ignored"}
+				}
+			],
+			"termination_error": {"message": "context deadline exceeded", "type": "context.deadlineExceededError"}
+		}`, string(data),
+	)
+}
+
+func TestOutcome_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(
+		retry.Outcome{
+			PolicyName:    "orders",
+			TotalAttempts: 3,
+			TotalDuration: 250 * time.Millisecond,
+			Status:        retry.OutcomeStatusError,
+			FailureReason: retry.OutcomeFailureReasonExhausted,
+		},
+	)
+	require.NoError(t, err)
+
+	require.JSONEq(
+		t, `{
+			"policy": "orders",
+			"total_attempts": 3,
+			"total_duration": "250ms",
+			"status": "error",
+			"failure_reason": "exhausted"
+		}`, string(data),
+	)
+}
+
+func TestRetryError_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(
+		slog.NewTextHandler(
+			&buf, &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if len(groups) == 0 && a.Key == slog.TimeKey {
+						return slog.Attr{}
+					}
+					return a
+				},
+			},
+		),
+	)
+
+	logger.Info("failed", "err", encodingRetryError())
+
+	out := buf.String()
+	require.Contains(t, out, `err.message="retry failed after 2 attempt(s): permanent"`)
+	require.Contains(t, out, "err.attempts.1.duration=1.5s")
+	require.Contains(t, out, "err.attempts.1.error.message=transient")
+	require.Contains(t, out, "err.attempts.1.error.type=*errors.errorString")
+	require.Contains(t, out, `err.attempts.1.decision="retry (classifier, delay 1s)"`)
+	require.Contains(t, out, `err.attempts.2.decision="stop (ignored)"`)
+	require.Contains(t, out, `err.termination_error.message="context deadline exceeded"`)
+}
+
+func TestJoinedRetryError_ErrorsIs(t *testing.T) {
+	joined := encodingRetryError().Joined()
+
+	require.ErrorIs(t, joined, errTransient)
+	require.ErrorIs(t, joined, errPermanent)
+	require.ErrorIs(t, joined, context.DeadlineExceeded)
+	require.Equal(t, []error{context.DeadlineExceeded, errTransient, errPermanent}, joined.Unwrap())
+
+	// the plain RetryError only matches the termination error
+	require.NotErrorIs(t, joined.RetryError, errTransient)
+
+	retryErr, ok := retry.AsRetryError(joined)
+	require.True(t, ok)
+	require.Same(t, joined.RetryError, retryErr)
+}
+
+func TestExecute_WithJoinedErrors(t *testing.T) {
+	policy := retry.MustNewPolicy(
+		"joined",
+		retry.WithMetrics(&retry.NoopMetrics{}),
+		retry.WithMaxAttempts(2),
+		retry.WithBackoff(backoff.NewFixed(time.Millisecond)),
+		retry.WithJoinedErrors(),
+	)
+
+	calls := 0
+	err := retry.Do(context.Background(), policy, func(context.Context) error {
+		calls++
+		if calls == 1 {
+			return errTransient
+		}
+		return errPermanent
+	})
+
+	var joined *retry.JoinedRetryError
+	require.True(t, errors.As(err, &joined))
+	require.ErrorIs(t, err, errTransient)
+	require.ErrorIs(t, err, errPermanent)
+
+	retryErr, ok := retry.AsRetryError(err)
+	require.True(t, ok)
+	require.Len(t, retryErr.Attempts, 2)
+
+	// clones keep the mode
+	err = retry.Do(context.Background(), policy.Clone("clone"), func(context.Context) error { return errTransient })
+	require.True(t, errors.As(err, &joined))
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/hugolhafner/dskit/circuitbreaker"
+	"github.com/hugolhafner/dskit/timeout"
 )
 
 type waiter func(time.Duration) error
@@ -44,7 +45,7 @@ func classifyAttemptFailure(err error) AttemptFailureReason {
 		return ""
 	}
 
-	if errors.Is(err, context.DeadlineExceeded) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, timeout.ErrTimeout) {
 		return AttemptFailureReasonTimeout
 	}
 
@@ -68,10 +69,9 @@ func classifyContextError(err error) OutcomeFailureReason {
 }
 
 type attemptOutcome[T any] struct {
-	result    T
-	attempt   Attempt
-	success   bool
-	retryable bool
+	result  T
+	attempt Attempt
+	success bool
 }
 
 func executeAttempt[T any](
@@ -120,19 +120,44 @@ func executeAttempt[T any](
 	if shouldRetryResult {
 		attempt.Error = ErrResultPredicateRetry
 		attempt.FailureReason = AttemptFailureReasonResult
-		attempt.Retryable = true
+		attempt.Decision = RetryDecision(DecisionReasonResult)
 	} else {
 		attempt.Error = attemptErr
 		attempt.FailureReason = classifyAttemptFailure(attemptErr)
-		attempt.Retryable = p.ShouldRetryError(attemptErr)
+		attempt.Decision = p.ClassifyError(attemptErr)
 	}
+	attempt.Retryable = attempt.Decision.Retry
 
 	var zero T
 	return attemptOutcome[T]{
-		result:    zero,
-		attempt:   attempt,
-		retryable: attempt.Retryable,
+		result:  zero,
+		attempt: attempt,
+	}
+}
+
+// decideNext applies the remaining attempts and the deadline of ctx to the decision of a
+// retryable attempt, and returns the wait before the next attempt
+func decideNext(ctx context.Context, p *Policy, attemptCount int, attempt *Attempt) time.Duration {
+	if !attempt.Decision.Retry {
+		return 0
+	}
+
+	if attemptCount >= p.maxAttempts {
+		attempt.Decision = StopDecision(DecisionReasonBudget)
+		return 0
 	}
+
+	delay := attempt.Decision.Delay
+	if delay <= 0 {
+		delay = p.backoff.Next(uint(attemptCount))
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		attempt.Decision = StopDecision(DecisionReasonDeadline)
+		return 0
+	}
+
+	return delay
 }
 
 func execute[T any](ctx context.Context, p *Policy, wait waiter, fn func(ctx context.Context) (T, error)) (T, error) {
@@ -160,6 +185,11 @@ func execute[T any](ctx context.Context, p *Policy, wait waiter, fn func(ctx con
 
 	for {
 		ao := executeAttempt(ctx, p, attemptCount, fn)
+
+		var backoffDuration time.Duration
+		if !ao.success {
+			backoffDuration = decideNext(ctx, p, attemptCount, &ao.attempt)
+		}
 		metricsReporter.RecordAttempt(ctx, ao.attempt)
 
 		if ao.success {
@@ -170,28 +200,41 @@ func execute[T any](ctx context.Context, p *Policy, wait waiter, fn func(ctx con
 
 		retryErr.Attempts = append(retryErr.Attempts, ao.attempt)
 
-		if !ao.retryable {
-			outcome.FailureReason = OutcomeFailureReasonNonRetryable
-			break
-		}
-
-		if attemptCount >= p.maxAttempts {
+		switch ao.attempt.Decision.Reason {
+		case DecisionReasonBudget:
 			outcome.FailureReason = OutcomeFailureReasonExhausted
+		case DecisionReasonDeadline:
+			// waiting would only run into the deadline, so fail now rather than once it passed
+			outcome.FailureReason = OutcomeFailureReasonTimeout
+			retryErr.TerminationError = ErrDeadlineBeforeRetry
+		default:
+			if !ao.attempt.Decision.Retry {
+				outcome.FailureReason = OutcomeFailureReasonNonRetryable
+			}
+		}
+		if !ao.attempt.Decision.Retry {
 			break
 		}
 
-		backoffDuration := p.backoff.Next(uint(attemptCount))
 		if waitErr := wait(backoffDuration); waitErr != nil {
 			outcome.FailureReason = classifyContextError(waitErr)
 			retryErr.TerminationError = waitErr
-			return result, retryErr
+			return result, p.retryError(retryErr)
 		}
 
 		attemptCount++
 		metricsReporter.RecordBackoff(ctx, p.name, attemptCount, backoffDuration)
 	}
 
-	return result, retryErr
+	return result, p.retryError(retryErr)
+}
+
+// retryError returns err in the form the policy was configured for, see WithJoinedErrors
+func (p *Policy) retryError(err *RetryError) error {
+	if p.joinErrors {
+		return err.Joined()
+	}
+	return err
 }
 
 func Do(ctx context.Context, p *Policy, fn func(context.Context) error) error {
@@ -201,7 +244,7 @@ func Do(ctx context.Context, p *Policy, fn func(context.Context) error) error {
 	return err
 }
 
-func DoWithCircuit(ctx context.Context, p *Policy, cb circuitbreaker.CircuitBreaker, fn func(context.Context) error) error {
+func DoWithCircuit(ctx context.Context, p *Policy, cb circuitbreaker.Guard, fn func(context.Context) error) error {
 	_, err := ExecuteWithCircuit(ctx, p, cb, func(ctx context.Context) (struct{}, error) {
 		return struct{}{}, fn(ctx)
 	})
@@ -212,7 +255,7 @@ func Execute[T any](ctx context.Context, p *Policy, fn func(context.Context) (T,
 	return execute(ctx, p, contextWaiter(ctx), fn)
 }
 
-func ExecuteWithCircuit[T any](ctx context.Context, p *Policy, cb circuitbreaker.CircuitBreaker, fn func(context.Context) (T, error)) (T, error) {
+func ExecuteWithCircuit[T any](ctx context.Context, p *Policy, cb circuitbreaker.Guard, fn func(context.Context) (T, error)) (T, error) {
 	return execute(ctx, p, contextWaiter(ctx), func(ctx context.Context) (T, error) {
 		return circuitbreaker.Execute[T](ctx, cb, fn)
 	})
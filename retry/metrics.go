@@ -55,7 +55,11 @@ type Attempt struct {
 	Status        AttemptStatus
 	FailureReason AttemptFailureReason
 	Error         error
-	Retryable     bool
+
+	// Retryable is whether the error was classified as retryable, Decision is whether the attempt was
+	// retried after also taking the remaining attempts and the deadline into account
+	Retryable bool
+	Decision  Decision
 }
 
 func (a Attempt) IsSuccess() bool {
@@ -2,64 +2,204 @@ package retry
 
 import (
 	"context"
-	"sync/atomic"
+	"maps"
+	"sync"
 	"time"
 )
 
-type InMemoryMetrics struct {
-	attemptsTotal         atomic.Int64
-	attemptsSuccess       atomic.Int64
-	attemptsFailure       atomic.Int64
-	attemptsDurationTotal atomic.Int64
+// LatencySummary contains latency percentiles estimated from a streaming quantile sketch
+type LatencySummary struct {
+	Count int64
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+}
+
+// PolicyStats contains the metrics recorded for a single retry policy, or for all policies combined
+type PolicyStats struct {
+	Attempts              int64
+	AttemptsSuccess       int64
+	AttemptsFailure       int64
+	AttemptFailureReasons map[AttemptFailureReason]int64
+	AttemptsDurationTotal time.Duration
+	AttemptLatency        LatencySummary
+
+	Outcomes              int64
+	OutcomesSuccess       int64
+	OutcomesFailure       int64
+	OutcomeFailureReasons map[OutcomeFailureReason]int64
+	OutcomesDurationTotal time.Duration
+	OutcomeLatency        LatencySummary
 
-	outcomeTotal         atomic.Int64
-	outcomeSuccess       atomic.Int64
-	outcomeFailure       atomic.Int64
-	outcomeDurationTotal atomic.Int64
+	// AttemptsPerOutcome is a histogram of the number of attempts it took to reach an outcome
+	AttemptsPerOutcome map[int]int64
 
-	backoffDurationTotal atomic.Int64
+	Backoffs             int64
+	BackoffDurationTotal time.Duration
 }
 
-var _ Metrics = (*InMemoryMetrics)(nil)
+// InMemorySnapshot is a point-in-time copy of the metrics held by InMemoryMetrics
+type InMemorySnapshot struct {
+	Total    PolicyStats
+	Policies map[string]PolicyStats
+}
 
-func NewInMemoryMetrics() *InMemoryMetrics {
-	return &InMemoryMetrics{}
+type policyRecorder struct {
+	stats          PolicyStats
+	attemptLatency *quantileSketch
+	outcomeLatency *quantileSketch
 }
 
-func (m *InMemoryMetrics) RecordAttempt(_ context.Context, attempt Attempt) {
-	m.attemptsTotal.Add(1)
+func newPolicyRecorder() *policyRecorder {
+	return &policyRecorder{
+		stats: PolicyStats{
+			AttemptFailureReasons: make(map[AttemptFailureReason]int64),
+			OutcomeFailureReasons: make(map[OutcomeFailureReason]int64),
+			AttemptsPerOutcome:    make(map[int]int64),
+		},
+		attemptLatency: newQuantileSketch(defaultSketchAccuracy),
+		outcomeLatency: newQuantileSketch(defaultSketchAccuracy),
+	}
+}
+
+func (r *policyRecorder) recordAttempt(attempt Attempt) {
+	r.stats.Attempts++
 	if attempt.IsSuccess() {
-		m.attemptsSuccess.Add(1)
+		r.stats.AttemptsSuccess++
 	} else {
-		m.attemptsFailure.Add(1)
+		r.stats.AttemptsFailure++
+		r.stats.AttemptFailureReasons[attempt.FailureReason]++
 	}
-	m.attemptsDurationTotal.Add(attempt.Duration.Milliseconds())
+	r.stats.AttemptsDurationTotal += attempt.Duration
+	r.attemptLatency.Add(float64(attempt.Duration))
 }
 
-func (m *InMemoryMetrics) RecordOutcome(_ context.Context, outcome Outcome) {
-	m.outcomeTotal.Add(1)
+func (r *policyRecorder) recordOutcome(outcome Outcome) {
+	r.stats.Outcomes++
 	if outcome.IsSuccess() {
-		m.outcomeSuccess.Add(1)
+		r.stats.OutcomesSuccess++
 	} else {
-		m.outcomeFailure.Add(1)
+		r.stats.OutcomesFailure++
+		r.stats.OutcomeFailureReasons[outcome.FailureReason]++
+	}
+	r.stats.OutcomesDurationTotal += outcome.TotalDuration
+	r.stats.AttemptsPerOutcome[outcome.TotalAttempts]++
+	r.outcomeLatency.Add(float64(outcome.TotalDuration))
+}
+
+func (r *policyRecorder) recordBackoff(duration time.Duration) {
+	r.stats.Backoffs++
+	r.stats.BackoffDurationTotal += duration
+}
+
+func summarize(s *quantileSketch) LatencySummary {
+	return LatencySummary{
+		Count: int64(s.Count()),
+		P50:   time.Duration(s.Quantile(0.50)),
+		P90:   time.Duration(s.Quantile(0.90)),
+		P99:   time.Duration(s.Quantile(0.99)),
+	}
+}
+
+func (r *policyRecorder) snapshot() PolicyStats {
+	stats := r.stats
+	stats.AttemptFailureReasons = maps.Clone(r.stats.AttemptFailureReasons)
+	stats.OutcomeFailureReasons = maps.Clone(r.stats.OutcomeFailureReasons)
+	stats.AttemptsPerOutcome = maps.Clone(r.stats.AttemptsPerOutcome)
+	stats.AttemptLatency = summarize(r.attemptLatency)
+	stats.OutcomeLatency = summarize(r.outcomeLatency)
+	return stats
+}
+
+// InMemoryMetrics keeps retry metrics in memory, both in total and broken down per policy
+type InMemoryMetrics struct {
+	mu       sync.Mutex
+	total    *policyRecorder
+	policies map[string]*policyRecorder
+}
+
+var _ Metrics = (*InMemoryMetrics)(nil)
+
+func NewInMemoryMetrics() *InMemoryMetrics {
+	return &InMemoryMetrics{
+		total:    newPolicyRecorder(),
+		policies: make(map[string]*policyRecorder),
+	}
+}
+
+func (m *InMemoryMetrics) policyUnsafe(name string) *policyRecorder {
+	r, ok := m.policies[name]
+	if !ok {
+		r = newPolicyRecorder()
+		m.policies[name] = r
+	}
+
+	return r
+}
+
+func (m *InMemoryMetrics) RecordAttempt(_ context.Context, attempt Attempt) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.total.recordAttempt(attempt)
+	m.policyUnsafe(attempt.PolicyName).recordAttempt(attempt)
+}
+
+func (m *InMemoryMetrics) RecordOutcome(_ context.Context, outcome Outcome) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.total.recordOutcome(outcome)
+	m.policyUnsafe(outcome.PolicyName).recordOutcome(outcome)
+}
+
+func (m *InMemoryMetrics) RecordBackoff(_ context.Context, policyName string, _ int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.total.recordBackoff(duration)
+	m.policyUnsafe(policyName).recordBackoff(duration)
+}
+
+// Snapshot returns a copy of the recorded metrics, in total and per policy
+func (m *InMemoryMetrics) Snapshot() InMemorySnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := InMemorySnapshot{
+		Total:    m.total.snapshot(),
+		Policies: make(map[string]PolicyStats, len(m.policies)),
+	}
+
+	for name, r := range m.policies {
+		snapshot.Policies[name] = r.snapshot()
 	}
-	m.outcomeDurationTotal.Add(outcome.TotalDuration.Milliseconds())
+
+	return snapshot
 }
 
-func (m *InMemoryMetrics) RecordBackoff(_ context.Context, _ string, _ int, duration time.Duration) {
-	m.backoffDurationTotal.Add(duration.Milliseconds())
+// Reset discards all recorded metrics
+func (m *InMemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.total = newPolicyRecorder()
+	m.policies = make(map[string]*policyRecorder)
 }
 
+// GetMetrics returns the totals across all policies, durations are in milliseconds
 func (m *InMemoryMetrics) GetMetrics() map[string]int64 {
+	total := m.Snapshot().Total
+
 	return map[string]int64{
-		"attempts_total":          m.attemptsTotal.Load(),
-		"attempts_success":        m.attemptsSuccess.Load(),
-		"attempts_failure":        m.attemptsFailure.Load(),
-		"attempts_duration_total": m.attemptsDurationTotal.Load(),
-		"outcome_total":           m.outcomeTotal.Load(),
-		"outcome_success":         m.outcomeSuccess.Load(),
-		"outcome_failure":         m.outcomeFailure.Load(),
-		"outcome_duration_total":  m.outcomeDurationTotal.Load(),
-		"backoff_duration_total":  m.backoffDurationTotal.Load(),
+		"attempts_total":          total.Attempts,
+		"attempts_success":        total.AttemptsSuccess,
+		"attempts_failure":        total.AttemptsFailure,
+		"attempts_duration_total": total.AttemptsDurationTotal.Milliseconds(),
+		"outcome_total":           total.Outcomes,
+		"outcome_success":         total.OutcomesSuccess,
+		"outcome_failure":         total.OutcomesFailure,
+		"outcome_duration_total":  total.OutcomesDurationTotal.Milliseconds(),
+		"backoff_duration_total":  total.BackoffDurationTotal.Milliseconds(),
 	}
 }
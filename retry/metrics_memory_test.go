@@ -0,0 +1,83 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/backoff"
+)
+
+func TestQuantileSketch_RelativeAccuracy(t *testing.T) {
+	s := newQuantileSketch(defaultSketchAccuracy)
+	r := rand.New(rand.NewPCG(1, 2))
+
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = float64(i + 1)
+	}
+	r.Shuffle(len(values), func(i, j int) { values[i], values[j] = values[j], values[i] })
+
+	for _, v := range values {
+		s.Add(v)
+	}
+
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		want := q * 9999
+		got := s.Quantile(q)
+		require.LessOrEqual(t, math.Abs(got-want)/want, 2*defaultSketchAccuracy, "quantile %v", q)
+	}
+
+	require.Equal(t, float64(1), s.Quantile(0))
+	require.Equal(t, float64(10000), s.Quantile(1))
+}
+
+func TestInMemoryMetrics_PerPolicy(t *testing.T) {
+	m := NewInMemoryMetrics()
+	errBoom := errors.New("boom")
+
+	flaky := MustNewPolicy("flaky", WithMetrics(m), WithMaxAttempts(3), WithBackoff(backoff.NewFixed(time.Microsecond)))
+	stable := MustNewPolicy("stable", WithMetrics(m))
+
+	calls := 0
+	_, err := Execute(context.Background(), flaky, func(context.Context) (int, error) {
+		calls++
+		if calls < 2 {
+			return 0, errBoom
+		}
+		return calls, nil
+	})
+	require.NoError(t, err)
+
+	err = Do(context.Background(), flaky, func(context.Context) error { return errBoom })
+	require.Error(t, err)
+
+	require.NoError(t, Do(context.Background(), stable, func(context.Context) error { return nil }))
+
+	snapshot := m.Snapshot()
+	require.Equal(t, int64(6), snapshot.Total.Attempts)
+	require.Equal(t, int64(3), snapshot.Total.Outcomes)
+
+	flakyStats := snapshot.Policies["flaky"]
+	require.Equal(t, int64(5), flakyStats.Attempts)
+	require.Equal(t, int64(4), flakyStats.AttemptFailureReasons[AttemptFailureReasonError])
+	require.Equal(t, int64(1), flakyStats.OutcomeFailureReasons[OutcomeFailureReasonExhausted])
+	require.Equal(t, map[int]int64{2: 1, 3: 1}, flakyStats.AttemptsPerOutcome)
+	require.Equal(t, int64(3), flakyStats.Backoffs)
+	require.Equal(t, int64(5), flakyStats.AttemptLatency.Count)
+
+	stableStats := snapshot.Policies["stable"]
+	require.Equal(t, int64(1), stableStats.OutcomesSuccess)
+	require.Equal(t, map[int]int64{1: 1}, stableStats.AttemptsPerOutcome)
+
+	require.Equal(t, int64(6), m.GetMetrics()["attempts_total"])
+
+	m.Reset()
+	require.Empty(t, m.Snapshot().Policies)
+	require.Zero(t, m.GetMetrics()["attempts_total"])
+}
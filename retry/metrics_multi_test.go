@@ -0,0 +1,81 @@
+package retry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/retry"
+)
+
+type panickingMetrics struct {
+	retry.NoopMetrics
+}
+
+func (p *panickingMetrics) RecordAttempt(_ context.Context, _ retry.Attempt) {
+	panic("faulty exporter")
+}
+
+type blockingMetrics struct {
+	retry.NoopMetrics
+	release chan struct{}
+}
+
+func (b *blockingMetrics) RecordAttempt(_ context.Context, _ retry.Attempt) {
+	<-b.release
+}
+
+func TestMultiMetrics_IsolatesPanics(t *testing.T) {
+	var recovered []any
+	retry.SetMetricsPanicHandler(func(r any) { recovered = append(recovered, r) })
+	t.Cleanup(func() { retry.SetMetricsPanicHandler(nil) })
+	panics := retry.MetricsPanics()
+
+	first := retry.NewInMemoryMetrics()
+	second := retry.NewInMemoryMetrics()
+
+	m := retry.MultiMetrics(first, &panickingMetrics{}, nil, second)
+
+	require.NotPanics(t, func() {
+		m.RecordAttempt(context.Background(), retry.Attempt{Status: retry.AttemptStatusSuccess})
+	})
+
+	require.Equal(t, int64(1), first.GetMetrics()["attempts_total"])
+	require.Equal(t, int64(1), second.GetMetrics()["attempts_total"])
+	require.Equal(t, []any{"faulty exporter"}, recovered)
+	require.Equal(t, panics+1, retry.MetricsPanics())
+}
+
+func TestAsyncMetrics_DeliversOnClose(t *testing.T) {
+	sink := retry.NewInMemoryMetrics()
+	m := retry.NewAsyncMetrics(sink, 16)
+
+	for i := 0; i < 10; i++ {
+		m.RecordOutcome(context.Background(), retry.Outcome{Status: retry.OutcomeStatusSuccess})
+	}
+	m.Close()
+
+	require.Equal(t, int64(10), sink.GetMetrics()["outcome_total"])
+	require.Zero(t, m.Dropped())
+}
+
+func TestAsyncMetrics_DropsWhenFull(t *testing.T) {
+	sink := &blockingMetrics{release: make(chan struct{})}
+	m := retry.NewAsyncMetrics(sink, 1)
+
+	// the first event is picked up by the worker and blocks it, the second fills the buffer
+	m.RecordAttempt(context.Background(), retry.Attempt{})
+	require.Eventually(t, func() bool {
+		m.RecordAttempt(context.Background(), retry.Attempt{})
+		return m.Dropped() > 0
+	}, time.Second, time.Millisecond)
+
+	close(sink.release)
+	m.Close()
+
+	dropped := m.Dropped()
+	m.RecordAttempt(context.Background(), retry.Attempt{})
+	require.Equal(t, dropped+1, m.Dropped())
+}
@@ -19,27 +19,31 @@ import (
 //
 // retry_attempts_failure_total (Counter) - Total number of failed retry attempts
 // * policy (string) - The name of the retry policy
-// * reason (string) - The reason for failure ("error", "timeout", "canceled", "status")
+// * reason (string) - The reason for failure ("error", "timeout", "canceled", "result")
 // * retryable (bool) - Whether the failure was considered retryable
+// * decision (string) - Why the attempt was or was not retried ("retryable", "allowlist", "ignored",
+// "not_in_allowlist", "predicate", "result", "classifier", "budget", "deadline")
 //
 // retry_attempts_duration_milliseconds (Histogram) - Duration of retry attempts in milliseconds
 // * policy (string) - The name of the retry policy
-// * outcome (string) - The outcome of the attempt ("success", "failure")
+// * status (string) - The status of the attempt ("success", "error")
 //
 // retry_attempts_buckets (Histogram) - Buckets for retry attempt counts
 // * policy (string) - The name of the retry policy
 //
 // retry_outcome_total (Counter) - Total number of retry outcomes
+// * policy (string) - The name of the retry policy
 //
 // retry_outcome_success_total (Counter) - Total number of successful retry outcomes
 // * policy (string) - The name of the retry policy
 //
 // retry_outcome_failure_total (Counter) - Total number of failed retry outcomes
 // * policy (string) - The name of the retry policy
-// * reason (string) - The reason for failure ("exhausted", "canceled", "non_retryable")
+// * reason (string) - The reason for failure ("exhausted", "timeout", "canceled", "non_retryable")
 //
 // retry_outcome_duration_milliseconds (Histogram) - Duration of retry outcome in milliseconds
 // * policy (string) - The name of the retry policy
+// * status (string) - The status of the outcome ("success", "error")
 //
 // retry_backoff_duration_milliseconds (Histogram) - Duration of backoff periods in milliseconds
 // * policy (string) - The name of the retry policy
@@ -251,6 +255,7 @@ func (m *OTelMetrics) RecordAttempt(ctx context.Context, attempt Attempt) {
 					baseAttrs,
 					attribute.String("reason", string(attempt.FailureReason)),
 					attribute.Bool("retryable", attempt.Retryable),
+					attribute.String("decision", string(attempt.Decision.Reason)),
 				)...,
 			),
 		)
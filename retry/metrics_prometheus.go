@@ -0,0 +1,167 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ Metrics = (*PrometheusMetrics)(nil)
+
+// PrometheusMetrics exposes the same metric names and labels as OTelMetrics using the
+// Prometheus client directly, for services that do not run an OpenTelemetry SDK.
+type PrometheusMetrics struct {
+	attemptsTotal    *prometheus.CounterVec
+	attemptsSuccess  *prometheus.CounterVec
+	attemptsFailure  *prometheus.CounterVec
+	attemptsDuration *prometheus.HistogramVec
+
+	outcomeTotal          *prometheus.CounterVec
+	outcomeSuccess        *prometheus.CounterVec
+	outcomeFailure        *prometheus.CounterVec
+	outcomeDuration       *prometheus.HistogramVec
+	outcomeAttemptsBucket *prometheus.HistogramVec
+
+	backoffDuration *prometheus.HistogramVec
+}
+
+type PrometheusConfig struct {
+	Registerer   prometheus.Registerer
+	MetricPrefix string
+	ConstLabels  prometheus.Labels
+}
+
+type PrometheusOption func(*PrometheusConfig)
+
+func WithPrometheusRegisterer(registerer prometheus.Registerer) PrometheusOption {
+	return func(cfg *PrometheusConfig) {
+		cfg.Registerer = registerer
+	}
+}
+
+func WithPrometheusMetricPrefix(prefix string) PrometheusOption {
+	return func(cfg *PrometheusConfig) {
+		cfg.MetricPrefix = prefix
+	}
+}
+
+func WithPrometheusConstLabels(labels prometheus.Labels) PrometheusOption {
+	return func(cfg *PrometheusConfig) {
+		cfg.ConstLabels = maps.Clone(labels)
+	}
+}
+
+func NewPrometheusMetrics(opts ...PrometheusOption) (*PrometheusMetrics, error) {
+	cfg := &PrometheusConfig{
+		Registerer:   prometheus.DefaultRegisterer,
+		MetricPrefix: "retry_",
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	durationBuckets := []float64{0, 1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: cfg.MetricPrefix + name, Help: help, ConstLabels: cfg.ConstLabels}, labels,
+		)
+	}
+
+	histogram := func(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name: cfg.MetricPrefix + name, Help: help, ConstLabels: cfg.ConstLabels, Buckets: buckets,
+			}, labels,
+		)
+	}
+
+	m := &PrometheusMetrics{
+		attemptsTotal:   counter("attempts_total", "Total number of retry attempts made", "policy"),
+		attemptsSuccess: counter("attempts_success_total", "Total number of successful retry attempts", "policy"),
+		attemptsFailure: counter(
+			"attempts_failure_total", "Total number of failed retry attempts", "policy", "reason", "retryable",
+			"decision",
+		),
+		attemptsDuration: histogram(
+			"attempts_duration_milliseconds", "Duration of retry attempts in milliseconds", durationBuckets,
+			"policy", "status",
+		),
+		outcomeAttemptsBucket: histogram(
+			"attempts_buckets", "Buckets for retry attempt counts",
+			[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 15, 20, 30, 50, 100}, "policy",
+		),
+		outcomeTotal:   counter("outcome_total", "Total number of retry outcomes", "policy"),
+		outcomeSuccess: counter("outcome_success_total", "Total number of successful retry outcomes", "policy"),
+		outcomeFailure: counter(
+			"outcome_failure_total", "Total number of failed retry outcomes", "policy", "reason",
+		),
+		outcomeDuration: histogram(
+			"outcome_duration_milliseconds", "Duration of retry outcome in milliseconds", durationBuckets,
+			"policy", "status",
+		),
+		backoffDuration: histogram(
+			"backoff_duration_milliseconds", "Duration of backoff periods in milliseconds", durationBuckets, "policy",
+		),
+	}
+
+	collectors := []prometheus.Collector{
+		m.attemptsTotal, m.attemptsSuccess, m.attemptsFailure, m.attemptsDuration,
+		m.outcomeTotal, m.outcomeSuccess, m.outcomeFailure, m.outcomeDuration, m.outcomeAttemptsBucket,
+		m.backoffDuration,
+	}
+
+	for _, c := range collectors {
+		if err := cfg.Registerer.Register(c); err != nil {
+			return nil, fmt.Errorf("failed to register prometheus collector: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+func MustNewPrometheusMetrics(opts ...PrometheusOption) *PrometheusMetrics {
+	m, err := NewPrometheusMetrics(opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+func (m *PrometheusMetrics) RecordAttempt(_ context.Context, attempt Attempt) {
+	m.attemptsTotal.WithLabelValues(attempt.PolicyName).Inc()
+	m.attemptsDuration.WithLabelValues(attempt.PolicyName, string(attempt.Status)).
+		Observe(float64(attempt.Duration.Milliseconds()))
+
+	if attempt.IsSuccess() {
+		m.attemptsSuccess.WithLabelValues(attempt.PolicyName).Inc()
+	} else {
+		m.attemptsFailure.WithLabelValues(
+			attempt.PolicyName, string(attempt.FailureReason), strconv.FormatBool(attempt.Retryable),
+			string(attempt.Decision.Reason),
+		).Inc()
+	}
+}
+
+func (m *PrometheusMetrics) RecordOutcome(_ context.Context, outcome Outcome) {
+	m.outcomeTotal.WithLabelValues(outcome.PolicyName).Inc()
+	m.outcomeAttemptsBucket.WithLabelValues(outcome.PolicyName).Observe(float64(outcome.TotalAttempts))
+	m.outcomeDuration.WithLabelValues(outcome.PolicyName, string(outcome.Status)).
+		Observe(float64(outcome.TotalDuration.Milliseconds()))
+
+	if outcome.IsSuccess() {
+		m.outcomeSuccess.WithLabelValues(outcome.PolicyName).Inc()
+	} else {
+		m.outcomeFailure.WithLabelValues(outcome.PolicyName, string(outcome.FailureReason)).Inc()
+	}
+}
+
+func (m *PrometheusMetrics) RecordBackoff(_ context.Context, policyName string, _ int, duration time.Duration) {
+	m.backoffDuration.WithLabelValues(policyName).Observe(float64(duration.Milliseconds()))
+}
@@ -0,0 +1,91 @@
+package retry_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/backoff"
+	"github.com/hugolhafner/dskit/retry"
+)
+
+func TestPrometheusMetrics_RecordsExecution(t *testing.T) {
+	promRegistry := prometheus.NewPedanticRegistry()
+	metrics := retry.MustNewPrometheusMetrics(retry.WithPrometheusRegisterer(promRegistry))
+
+	policy := retry.MustNewPolicy(
+		"orders",
+		retry.WithMetrics(metrics),
+		retry.WithMaxAttempts(2),
+		retry.WithBackoff(backoff.NewFixed(time.Millisecond)),
+	)
+	_ = retry.Do(context.Background(), policy, func(context.Context) error { return errTransient })
+
+	expected := `
+# HELP retry_attempts_failure_total Total number of failed retry attempts
+# TYPE retry_attempts_failure_total counter
+retry_attempts_failure_total{decision="budget",policy="orders",reason="error",retryable="true"} 1
+retry_attempts_failure_total{decision="retryable",policy="orders",reason="error",retryable="true"} 1
+# HELP retry_attempts_total Total number of retry attempts made
+# TYPE retry_attempts_total counter
+retry_attempts_total{policy="orders"} 2
+# HELP retry_outcome_failure_total Total number of failed retry outcomes
+# TYPE retry_outcome_failure_total counter
+retry_outcome_failure_total{policy="orders",reason="exhausted"} 1
+# HELP retry_outcome_total Total number of retry outcomes
+# TYPE retry_outcome_total counter
+retry_outcome_total{policy="orders"} 1
+`
+	require.NoError(
+		t, testutil.GatherAndCompare(
+			promRegistry, strings.NewReader(expected),
+			"retry_attempts_failure_total", "retry_attempts_total", "retry_outcome_failure_total", "retry_outcome_total",
+		),
+	)
+
+	for _, name := range []string{
+		"retry_attempts_duration_milliseconds", "retry_outcome_duration_milliseconds", "retry_attempts_buckets",
+		"retry_backoff_duration_milliseconds",
+	} {
+		count, err := testutil.GatherAndCount(promRegistry, name)
+		require.NoError(t, err)
+		require.Equal(t, 1, count, name)
+	}
+}
+
+func TestPrometheusMetrics_PrefixAndConstLabels(t *testing.T) {
+	promRegistry := prometheus.NewPedanticRegistry()
+	metrics := retry.MustNewPrometheusMetrics(
+		retry.WithPrometheusRegisterer(promRegistry),
+		retry.WithPrometheusMetricPrefix("client_retry_"),
+		retry.WithPrometheusConstLabels(prometheus.Labels{"service": "checkout"}),
+	)
+
+	metrics.RecordOutcome(
+		context.Background(), retry.Outcome{PolicyName: "search", TotalAttempts: 1, Status: retry.OutcomeStatusSuccess},
+	)
+
+	expected := `
+# HELP client_retry_outcome_success_total Total number of successful retry outcomes
+# TYPE client_retry_outcome_success_total counter
+client_retry_outcome_success_total{policy="search",service="checkout"} 1
+`
+	require.NoError(
+		t, testutil.GatherAndCompare(
+			promRegistry, strings.NewReader(expected), "client_retry_outcome_success_total",
+		),
+	)
+
+	// a second instance on the same registerer collides with the first
+	_, err := retry.NewPrometheusMetrics(
+		retry.WithPrometheusRegisterer(promRegistry),
+		retry.WithPrometheusMetricPrefix("client_retry_"),
+		retry.WithPrometheusConstLabels(prometheus.Labels{"service": "checkout"}),
+	)
+	require.Error(t, err)
+}
@@ -1,7 +1,6 @@
 package retry
 
 import (
-	"errors"
 	"time"
 
 	"github.com/hugolhafner/dskit/backoff"
@@ -40,6 +39,12 @@ type Policy struct {
 
 	// ignoreErrors is a list of error types that should not trigger a retry
 	ignoreErrors []error
+
+	// classifier decides which errors are retried, taking precedence over the predicate and lists
+	classifier Classifier
+
+	// joinErrors returns a JoinedRetryError instead of a RetryError on failure
+	joinErrors bool
 }
 
 type Option func(*Policy)
@@ -85,6 +90,22 @@ func WithRetryOnErrorPredicate(predicate func(error) bool) Option {
 	}
 }
 
+// WithClassifier sets a Classifier deciding which errors are retried. If this exists, it takes
+// precedence over the error predicate and the retryErrors and ignoreErrors lists.
+func WithClassifier(classifier Classifier) Option {
+	return func(p *Policy) {
+		p.classifier = classifier
+	}
+}
+
+// WithJoinedErrors makes the policy fail with a JoinedRetryError, so errors.Is and errors.As match
+// the error of any attempt instead of only the last one.
+func WithJoinedErrors() Option {
+	return func(p *Policy) {
+		p.joinErrors = true
+	}
+}
+
 func WithRetryErrors(errors ...error) Option {
 	return func(p *Policy) {
 		p.retryErrors = append(p.retryErrors, errors...)
@@ -169,33 +190,9 @@ func (p *Policy) metricsReporter() Metrics {
 	return GetGlobalMetrics()
 }
 
+// ShouldRetryError reports whether an attempt that failed with err is retried, see ClassifyError
 func (p *Policy) ShouldRetryError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	if p.retryOnErrorPredicate != nil {
-		return p.retryOnErrorPredicate(err)
-	}
-
-	for _, ignoreErr := range p.ignoreErrors {
-		if errors.Is(err, ignoreErr) {
-			return false
-		}
-	}
-
-	// If allowlist is defined, error must match
-	if len(p.retryErrors) > 0 {
-		for _, retryErr := range p.retryErrors {
-			if errors.Is(err, retryErr) {
-				return true
-			}
-		}
-
-		return false
-	}
-
-	return true
+	return p.ClassifyError(err).Retry
 }
 
 func (p *Policy) Name() string {
@@ -211,6 +208,8 @@ func (p *Policy) Clone(name string) *Policy {
 		backoff:                p.backoff,
 		retryOnResultPredicate: p.retryOnResultPredicate,
 		retryOnErrorPredicate:  p.retryOnErrorPredicate,
+		classifier:             p.classifier,
+		joinErrors:             p.joinErrors,
 		retryErrors:            nil,
 		ignoreErrors:           nil,
 	}
@@ -248,6 +247,10 @@ func (p *Policy) RetryOnErrorPredicate() func(error) bool {
 	return p.retryOnErrorPredicate
 }
 
+func (p *Policy) Classifier() Classifier {
+	return p.classifier
+}
+
 func (p *Policy) RetryErrors() []error {
 	if len(p.retryErrors) == 0 {
 		return nil
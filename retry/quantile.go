@@ -0,0 +1,89 @@
+package retry
+
+import (
+	"math"
+	"slices"
+)
+
+// quantileSketch is a streaming quantile estimator with bounded relative error.
+// Values are mapped to logarithmically sized buckets, so any reported quantile is
+// within relativeAccuracy of the true value while memory grows only with the
+// logarithm of the value range rather than with the number of samples.
+type quantileSketch struct {
+	gamma       float64
+	logGamma    float64
+	buckets     map[int]uint64
+	zeroCount   uint64
+	count       uint64
+	minObserved float64
+	maxObserved float64
+}
+
+const defaultSketchAccuracy = 0.01
+
+func newQuantileSketch(relativeAccuracy float64) *quantileSketch {
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &quantileSketch{
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		buckets:  make(map[int]uint64),
+	}
+}
+
+func (s *quantileSketch) Add(value float64) {
+	if s.count == 0 || value < s.minObserved {
+		s.minObserved = value
+	}
+	if s.count == 0 || value > s.maxObserved {
+		s.maxObserved = value
+	}
+	s.count++
+
+	if value <= 0 {
+		s.zeroCount++
+		return
+	}
+
+	s.buckets[int(math.Ceil(math.Log(value)/s.logGamma))]++
+}
+
+func (s *quantileSketch) Count() uint64 {
+	return s.count
+}
+
+// Quantile returns the estimated value at quantile q in [0, 1]
+func (s *quantileSketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+
+	if q <= 0 {
+		return s.minObserved
+	}
+	if q >= 1 {
+		return s.maxObserved
+	}
+
+	rank := uint64(q * float64(s.count-1))
+	if rank < s.zeroCount {
+		return 0
+	}
+
+	keys := make([]int, 0, len(s.buckets))
+	for k := range s.buckets {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	seen := s.zeroCount
+	for _, k := range keys {
+		seen += s.buckets[k]
+		if seen > rank {
+			// midpoint of the bucket (gamma^(k-1), gamma^k] keeps the relative error symmetric
+			value := 2 * math.Pow(s.gamma, float64(k)) / (s.gamma + 1)
+			return math.Min(math.Max(value, s.minObserved), s.maxObserved)
+		}
+	}
+
+	return s.maxObserved
+}
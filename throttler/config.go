@@ -0,0 +1,102 @@
+package throttler
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+type Config struct {
+	// Classifier decides which calls count as accepted by the dependency, with the same
+	// semantics as the circuit breaker. Only failures reduce the accept count, ignored
+	// errors and slow calls are still accepts.
+	circuitbreaker.Classifier
+
+	Metrics Metrics
+
+	// K is the multiplier of accepts in the reject probability, lower values throttle
+	// more aggressively. 2 lets through twice as many requests as the dependency accepts.
+	K float64
+
+	// Window is the period over which requests and accepts are tracked
+	Window time.Duration
+
+	// Buckets is the number of buckets the window is divided into
+	Buckets int
+
+	// Now returns the current time, it can be replaced for deterministic tests
+	Now func() time.Time
+
+	// Random returns a number in [0, 1) used to decide whether to reject a call
+	Random func() float64
+}
+
+type Option func(*Config)
+
+func defaultConfig() Config {
+	return Config{
+		K:       2,
+		Window:  2 * time.Minute,
+		Buckets: 24,
+		Now:     time.Now,
+		Random:  rand.Float64,
+	}
+}
+
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Config) {
+		c.Metrics = metrics
+	}
+}
+
+func WithK(k float64) Option {
+	return func(c *Config) {
+		c.K = k
+	}
+}
+
+// WithWindow tracks requests over window divided into buckets. A window that is not positive keeps
+// the default, the number of buckets is clamped between 1 and the nanoseconds in the window.
+func WithWindow(window time.Duration, buckets int) Option {
+	return func(c *Config) {
+		c.Window = window
+		c.Buckets = buckets
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(c *Config) {
+		c.Now = now
+	}
+}
+
+func WithRandom(random func() float64) Option {
+	return func(c *Config) {
+		c.Random = random
+	}
+}
+
+func WithFailOnResultPredicate(predicate func(result any) bool) Option {
+	return func(c *Config) {
+		c.FailOnResultPredicate = predicate
+	}
+}
+
+func WithFailOnErrorPredicate(predicate func(error) bool) Option {
+	return func(c *Config) {
+		c.FailOnErrorPredicate = predicate
+	}
+}
+
+func WithFailErrors(errors ...error) Option {
+	return func(c *Config) {
+		c.FailErrors = errors
+	}
+}
+
+func WithIgnoreErrors(errors ...error) Option {
+	return func(c *Config) {
+		c.IgnoreErrors = errors
+	}
+}
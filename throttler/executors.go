@@ -0,0 +1,67 @@
+package throttler
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+)
+
+type PanicError struct {
+	Recover any
+	Cause   error
+	Stack   []byte
+}
+
+func (r *PanicError) Error() string {
+	return "throttler: panic occurred"
+}
+
+func (r *PanicError) Unwrap() error {
+	return r.Cause
+}
+
+func IsPanicError(err error) bool {
+	var panicError *PanicError
+	return errors.As(err, &panicError)
+}
+
+func safeExecute[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) (result T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{
+				Recover: r,
+				Cause:   err,
+				Stack:   debug.Stack(),
+			}
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// Execute runs fn unless the throttler rejects it locally with ErrThrottled. A panic in fn is
+// recovered and returned as a PanicError, which counts as a failure of the dependency.
+func Execute[T any](ctx context.Context, t *Throttler, fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	if err := t.before(ctx); err != nil {
+		return zero, err
+	}
+
+	start := t.config.Now()
+
+	result, err := safeExecute(ctx, fn)
+	t.after(ctx, result, err, t.config.Now().Sub(start))
+	return result, err
+}
+
+func Do(ctx context.Context, t *Throttler, fn func(context.Context) error) error {
+	_, err := Execute(ctx, t, func(ctx context.Context) (any, error) {
+		return nil, fn(ctx)
+	})
+
+	return err
+}
@@ -0,0 +1,80 @@
+package throttler
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/hugolhafner/dskit/circuitbreaker"
+)
+
+var _ Metrics = (*NoopMetrics)(nil)
+
+var _globalMetrics atomic.Pointer[Metrics]
+
+// CallResult represents the result of a call that was not throttled
+type CallResult struct {
+	Name     string
+	Outcome  circuitbreaker.CallOutcome
+	Duration time.Duration
+	Error    error
+}
+
+// CallRejection represents a call that was rejected locally
+type CallRejection struct {
+	Name              string
+	RejectProbability float64
+}
+
+// RejectProbability represents the reject probability computed for a call
+type RejectProbability struct {
+	Name        string
+	Probability float64
+	Requests    int64
+	Accepts     int64
+}
+
+// Metrics defines the interface for throttler instrumentation
+type Metrics interface {
+	// RecordCallResult records the result of a call that was not throttled
+	RecordCallResult(ctx context.Context, result CallResult)
+
+	// RecordCallRejection records a call that was rejected locally
+	RecordCallRejection(ctx context.Context, rejection CallRejection)
+
+	// RecordRejectProbability records the reject probability computed for every call
+	RecordRejectProbability(ctx context.Context, probability RejectProbability)
+}
+
+// NoopMetrics is a no-operation implementation of the Metrics interface
+type NoopMetrics struct{}
+
+func (n *NoopMetrics) RecordCallResult(_ context.Context, _ CallResult) {
+	// No-op
+}
+
+func (n *NoopMetrics) RecordCallRejection(_ context.Context, _ CallRejection) {
+	// No-op
+}
+
+func (n *NoopMetrics) RecordRejectProbability(_ context.Context, _ RejectProbability) {
+	// No-op
+}
+
+// SetGlobalMetrics sets the global Metrics implementation
+func SetGlobalMetrics(m Metrics) {
+	if m == nil {
+		m = &NoopMetrics{}
+	}
+
+	_globalMetrics.Store(&m)
+}
+
+// GetGlobalMetrics returns the global Metrics implementation
+func GetGlobalMetrics() Metrics {
+	m := _globalMetrics.Load()
+	if m == nil {
+		return &NoopMetrics{}
+	}
+	return *m
+}
@@ -0,0 +1,173 @@
+package throttler
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metrics:
+// throttler_calls_total (Counter) - Total number of calls that were not throttled
+// * name (string) - The name of the throttler
+// * outcome (string) - The outcome of the call ("success", "failure", "slow_success", "slow_failure", "timeout", "ignored")
+//
+// throttler_calls_duration_milliseconds (Histogram) - Duration of calls in milliseconds
+// * name (string) - The name of the throttler
+// * outcome (string) - The outcome of the call
+//
+// throttler_rejections_total (Counter) - Total number of calls rejected locally
+// * name (string) - The name of the throttler
+//
+// throttler_reject_probability (Gauge) - Current probability of rejecting a call
+// * name (string) - The name of the throttler
+
+const (
+	instrumentationName    = "github.com/hugolhafner/dskit/throttler"
+	instrumentationVersion = "v0.1.0" // x-release-please
+)
+
+const (
+	unitCall         = "{call}"
+	unitRejection    = "{rejection}"
+	unitMilliseconds = "ms"
+)
+
+var _ Metrics = (*OTelMetrics)(nil)
+
+type OTelMetrics struct {
+	callsTotal    metric.Int64Counter
+	callsDuration metric.Float64Histogram
+
+	rejectionsTotal metric.Int64Counter
+
+	rejectProbability metric.Float64Gauge
+
+	// attributes are added to every recorded measurement
+	attributes []attribute.KeyValue
+}
+
+type OTelConfig struct {
+	MeterProvider metric.MeterProvider
+	MetricPrefix  string
+	Attributes    []attribute.KeyValue
+}
+
+type OTelOption func(*OTelConfig)
+
+func WithMeterProvider(meterProvider metric.MeterProvider) OTelOption {
+	return func(cfg *OTelConfig) {
+		cfg.MeterProvider = meterProvider
+	}
+}
+
+func WithMetricPrefix(prefix string) OTelOption {
+	return func(cfg *OTelConfig) {
+		cfg.MetricPrefix = prefix
+	}
+}
+
+// WithAttributes adds attrs to every measurement, e.g. the service or the dependency
+func WithAttributes(attrs []attribute.KeyValue) OTelOption {
+	return func(cfg *OTelConfig) {
+		copied := make([]attribute.KeyValue, len(attrs))
+		copy(copied, attrs)
+		cfg.Attributes = copied
+	}
+}
+
+func NewOTelMetrics(opts ...OTelOption) (*OTelMetrics, error) {
+	cfg := &OTelConfig{
+		MeterProvider: otel.GetMeterProvider(),
+		MetricPrefix:  "throttler_",
+		Attributes:    []attribute.KeyValue{},
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	meter := cfg.MeterProvider.Meter(instrumentationName, metric.WithInstrumentationVersion(instrumentationVersion))
+
+	callsTotal, err := meter.Int64Counter(
+		cfg.MetricPrefix+"calls_total",
+		metric.WithDescription("Total number of calls that were not throttled"),
+		metric.WithUnit(unitCall),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calls_total counter: %w", err)
+	}
+
+	callsDuration, err := meter.Float64Histogram(
+		cfg.MetricPrefix+"calls_duration_milliseconds",
+		metric.WithDescription("Duration of calls in milliseconds"),
+		metric.WithUnit(unitMilliseconds),
+		metric.WithExplicitBucketBoundaries(0, 1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calls_duration_milliseconds histogram: %w", err)
+	}
+
+	rejectionsTotal, err := meter.Int64Counter(
+		cfg.MetricPrefix+"rejections_total",
+		metric.WithDescription("Total number of calls rejected locally"),
+		metric.WithUnit(unitRejection),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rejections_total counter: %w", err)
+	}
+
+	rejectProbability, err := meter.Float64Gauge(
+		cfg.MetricPrefix+"reject_probability",
+		metric.WithDescription("Current probability of rejecting a call"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reject_probability gauge: %w", err)
+	}
+
+	return &OTelMetrics{
+		callsTotal:        callsTotal,
+		callsDuration:     callsDuration,
+		rejectionsTotal:   rejectionsTotal,
+		rejectProbability: rejectProbability,
+		attributes:        cfg.Attributes,
+	}, nil
+}
+
+func MustNewOTelMetrics(opts ...OTelOption) *OTelMetrics {
+	m, err := NewOTelMetrics(opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+func (m *OTelMetrics) RecordCallResult(ctx context.Context, result CallResult) {
+	attrs := metric.WithAttributes(
+		m.withAttributes(
+			attribute.String("name", result.Name),
+			attribute.String("outcome", result.Outcome.String()),
+		)...,
+	)
+
+	m.callsTotal.Add(ctx, 1, attrs)
+	m.callsDuration.Record(ctx, float64(result.Duration.Milliseconds()), attrs)
+}
+
+func (m *OTelMetrics) RecordCallRejection(ctx context.Context, rejection CallRejection) {
+	m.rejectionsTotal.Add(ctx, 1, metric.WithAttributes(m.withAttributes(attribute.String("name", rejection.Name))...))
+}
+
+func (m *OTelMetrics) RecordRejectProbability(ctx context.Context, probability RejectProbability) {
+	m.rejectProbability.Record(
+		ctx, probability.Probability,
+		metric.WithAttributes(m.withAttributes(attribute.String("name", probability.Name))...),
+	)
+}
+
+func (m *OTelMetrics) withAttributes(attrs ...attribute.KeyValue) []attribute.KeyValue {
+	return append(attrs, m.attributes...)
+}
@@ -0,0 +1,164 @@
+package throttler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrThrottled = errors.New("throttler: request rejected locally")
+
+func IsThrottledError(err error) bool {
+	return errors.Is(err, ErrThrottled)
+}
+
+type bucket struct {
+	start    time.Time
+	requests int64
+	accepts  int64
+}
+
+// Throttler implements client-side adaptive throttling. It tracks the number of requests and
+// the number of requests accepted by the dependency over a sliding window and rejects new
+// requests locally with probability
+//
+//	max(0, (requests - K*accepts) / (requests + 1))
+//
+// Unlike a circuit breaker it never stops traffic completely, it sheds just enough load
+// to keep the request rate close to what the dependency is able to accept.
+type Throttler struct {
+	name   string
+	config Config
+
+	mu          sync.Mutex
+	buckets     []bucket
+	bucketWidth time.Duration
+}
+
+func New(name string, opts ...Option) *Throttler {
+	config := defaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	if config.Window <= 0 {
+		config.Window = defaultConfig().Window
+	}
+
+	// every bucket has to span at least a nanosecond
+	config.Buckets = int(min(max(int64(config.Buckets), 1), int64(config.Window)))
+
+	return &Throttler{
+		name:        name,
+		config:      config,
+		buckets:     make([]bucket, config.Buckets),
+		bucketWidth: config.Window / time.Duration(config.Buckets),
+	}
+}
+
+func (t *Throttler) Name() string {
+	return t.name
+}
+
+// RejectProbability returns the probability that the next request is rejected
+func (t *Throttler) RejectProbability() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	requests, accepts := t.totalsUnsafe(t.config.Now())
+	return t.rejectProbability(requests, accepts)
+}
+
+func (t *Throttler) rejectProbability(requests, accepts int64) float64 {
+	return math.Max(0, (float64(requests)-t.config.K*float64(accepts))/float64(requests+1))
+}
+
+func (t *Throttler) currentBucketUnsafe(now time.Time) *bucket {
+	start := now.Truncate(t.bucketWidth)
+	// times before 1970 have a negative bucket number, the modulo keeps its sign
+	n := int64(len(t.buckets))
+	idx := ((start.UnixNano()/int64(t.bucketWidth))%n + n) % n
+
+	b := &t.buckets[idx]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+
+	return b
+}
+
+func (t *Throttler) totalsUnsafe(now time.Time) (requests, accepts int64) {
+	for _, b := range t.buckets {
+		if now.Sub(b.start) < t.config.Window {
+			requests += b.requests
+			accepts += b.accepts
+		}
+	}
+
+	return requests, accepts
+}
+
+func (t *Throttler) before(ctx context.Context) error {
+	t.mu.Lock()
+
+	now := t.config.Now()
+	requests, accepts := t.totalsUnsafe(now)
+	probability := t.rejectProbability(requests, accepts)
+
+	// rejected requests count as requests, so the probability keeps rising while the dependency is failing
+	t.currentBucketUnsafe(now).requests++
+	rejected := probability > 0 && t.config.Random() < probability
+
+	t.mu.Unlock()
+
+	t.metricsReporter().RecordRejectProbability(
+		ctx, RejectProbability{
+			Name:        t.name,
+			Probability: probability,
+			Requests:    requests,
+			Accepts:     accepts,
+		},
+	)
+
+	if rejected {
+		t.metricsReporter().RecordCallRejection(
+			ctx, CallRejection{
+				Name:              t.name,
+				RejectProbability: probability,
+			},
+		)
+
+		return ErrThrottled
+	}
+
+	return nil
+}
+
+func (t *Throttler) after(ctx context.Context, result any, err error, duration time.Duration) {
+	outcome := t.config.Classify(result, err, duration)
+
+	if !outcome.IsFailure() {
+		t.mu.Lock()
+		t.currentBucketUnsafe(t.config.Now()).accepts++
+		t.mu.Unlock()
+	}
+
+	t.metricsReporter().RecordCallResult(
+		ctx, CallResult{
+			Name:     t.name,
+			Outcome:  outcome,
+			Duration: duration,
+			Error:    err,
+		},
+	)
+}
+
+func (t *Throttler) metricsReporter() Metrics {
+	if t.config.Metrics != nil {
+		return t.config.Metrics
+	}
+
+	return GetGlobalMetrics()
+}
@@ -0,0 +1,181 @@
+package throttler_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/hugolhafner/dskit/throttler"
+)
+
+var (
+	errUnavailable = errors.New("unavailable")
+	errNotFound    = errors.New("not found")
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestThrottler_RejectProbability(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	random := 0.99
+
+	th := throttler.New(
+		"test",
+		throttler.WithClock(clock.Now),
+		throttler.WithRandom(func() float64 { return random }),
+		throttler.WithWindow(time.Minute, 6),
+		throttler.WithIgnoreErrors(errNotFound),
+	)
+
+	// ignored errors mean the dependency accepted the request
+	for i := 0; i < 2; i++ {
+		require.ErrorIs(t, throttler.Do(context.Background(), th, func(context.Context) error { return errNotFound }), errNotFound)
+	}
+	for i := 0; i < 8; i++ {
+		require.ErrorIs(t, throttler.Do(context.Background(), th, func(context.Context) error { return errUnavailable }), errUnavailable)
+	}
+
+	// (10 - 2*2) / (10 + 1)
+	require.InDelta(t, 6.0/11.0, th.RejectProbability(), 1e-9)
+
+	random = 0.5
+	err := throttler.Do(context.Background(), th, func(context.Context) error { return nil })
+	require.ErrorIs(t, err, throttler.ErrThrottled)
+
+	// the rejected request is counted: (11 - 4) / 12
+	require.InDelta(t, 7.0/12.0, th.RejectProbability(), 1e-9)
+
+	random = 0.99
+	require.NoError(t, throttler.Do(context.Background(), th, func(context.Context) error { return nil }))
+
+	// requests age out of the window
+	clock.now = clock.now.Add(time.Minute)
+	require.Zero(t, th.RejectProbability())
+}
+
+func TestThrottler_NeverRejectsHealthyDependency(t *testing.T) {
+	th := throttler.New("healthy", throttler.WithRandom(func() float64 { return 0 }))
+
+	for i := 0; i < 100; i++ {
+		_, err := throttler.Execute(context.Background(), th, func(context.Context) (int, error) { return i, nil })
+		require.NoError(t, err)
+	}
+
+	require.Zero(t, th.RejectProbability())
+}
+
+func TestThrottler_RecoversPanics(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	th := throttler.New("panics", throttler.WithClock(clock.Now), throttler.WithRandom(func() float64 { return 0.99 }))
+
+	err := throttler.Do(context.Background(), th, func(context.Context) error { panic("boom") })
+	require.True(t, throttler.IsPanicError(err))
+
+	var panicErr *throttler.PanicError
+	require.ErrorAs(t, err, &panicErr)
+	require.Equal(t, "boom", panicErr.Recover)
+
+	// the panicking request was recorded as a failure: (1 - 0) / (1 + 1)
+	require.InDelta(t, 0.5, th.RejectProbability(), 1e-9)
+}
+
+func TestThrottler_TimesBeforeEpoch(t *testing.T) {
+	for _, now := range []time.Time{{}, time.Unix(-1000, 0)} {
+		clock := &fakeClock{now: now}
+		th := throttler.New("epoch", throttler.WithClock(clock.Now))
+
+		require.NotPanics(
+			t, func() {
+				err := throttler.Do(context.Background(), th, func(context.Context) error { return errUnavailable })
+				require.ErrorIs(t, err, errUnavailable)
+			},
+		)
+		require.InDelta(t, 0.5, th.RejectProbability(), 1e-9)
+	}
+}
+
+func TestThrottler_ClampsInvalidWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  time.Duration
+		buckets int
+	}{
+		{name: "zero window", window: 0, buckets: 10},
+		{name: "negative window", window: -time.Second, buckets: 10},
+		{name: "zero buckets", window: time.Minute, buckets: 0},
+		{name: "more buckets than nanoseconds", window: 5 * time.Nanosecond, buckets: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				clock := &fakeClock{now: time.Unix(1000, 0)}
+				th := throttler.New(
+					"window",
+					throttler.WithClock(clock.Now),
+					throttler.WithWindow(tt.window, tt.buckets),
+				)
+
+				require.NotPanics(
+					t, func() {
+						err := throttler.Do(context.Background(), th, func(context.Context) error { return errUnavailable })
+						require.ErrorIs(t, err, errUnavailable)
+						th.RejectProbability()
+					},
+				)
+			},
+		)
+	}
+}
+
+func TestOTelMetrics_Attributes(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	metrics := throttler.MustNewOTelMetrics(
+		throttler.WithMeterProvider(provider),
+		throttler.WithAttributes([]attribute.KeyValue{attribute.String("service", "checkout")}),
+	)
+	th := throttler.New("attributes", throttler.WithMetrics(metrics))
+	require.NoError(t, throttler.Do(context.Background(), th, func(context.Context) error { return nil }))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	want := attribute.NewSet(
+		attribute.String("name", "attributes"),
+		attribute.String("outcome", "success"),
+		attribute.String("service", "checkout"),
+	)
+
+	found := false
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "throttler_calls_total" {
+				continue
+			}
+
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			require.True(t, ok)
+			for _, dp := range sum.DataPoints {
+				if dp.Attributes.Equals(&want) {
+					require.Equal(t, int64(1), dp.Value)
+					found = true
+				}
+			}
+		}
+	}
+	require.True(t, found)
+}
@@ -0,0 +1,69 @@
+package timeout
+
+// Mode selects how an operation is stopped when it exceeds its timeout
+type Mode int
+
+const (
+	// ModeOptimistic cancels the context passed to the operation and waits for it to return.
+	// It relies on the operation honouring cancellation and never leaks a goroutine.
+	ModeOptimistic Mode = iota
+
+	// ModePessimistic runs the operation in its own goroutine and returns as soon as the
+	// timeout expires, abandoning the goroutine. Use it for operations that ignore their context.
+	ModePessimistic
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeOptimistic:
+		return "optimistic"
+	case ModePessimistic:
+		return "pessimistic"
+	default:
+		return "unknown"
+	}
+}
+
+type Config struct {
+	Name    string
+	Mode    Mode
+	Metrics Metrics
+
+	// OnAbandon is called from the abandoned goroutine once a pessimistic operation returns
+	// after its timeout expired or its parent context was cancelled
+	OnAbandon func(result any, err error)
+}
+
+type Option func(*Config)
+
+func WithName(name string) Option {
+	return func(c *Config) {
+		c.Name = name
+	}
+}
+
+func WithMode(mode Mode) Option {
+	return func(c *Config) {
+		c.Mode = mode
+	}
+}
+
+func WithMetrics(metrics Metrics) Option {
+	return func(c *Config) {
+		c.Metrics = metrics
+	}
+}
+
+func WithOnAbandon(fn func(result any, err error)) Option {
+	return func(c *Config) {
+		c.OnAbandon = fn
+	}
+}
+
+func (c *Config) metricsReporter() Metrics {
+	if c.Metrics != nil {
+		return c.Metrics
+	}
+
+	return GetGlobalMetrics()
+}
@@ -0,0 +1,43 @@
+package timeout
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout matches every error returned when an operation exceeds its timeout
+var ErrTimeout = errors.New("timeout: operation timed out")
+
+// Error is returned when an operation exceeds its timeout, it matches ErrTimeout
+// with errors.Is and unwraps to the error returned by the operation, if any
+type Error struct {
+	Timeout time.Duration
+	Mode    Mode
+	Cause   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("timeout: operation timed out after %v", e.Timeout)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+func IsTimeoutError(err error) bool {
+	return errors.Is(err, ErrTimeout)
+}
+
+// PanicError is passed to the abandon callback when an abandoned operation panics
+type PanicError struct {
+	Recover any
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("timeout: abandoned operation panicked: %v", p.Recover)
+}
@@ -0,0 +1,152 @@
+package timeout
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// Execute runs fn with a context that expires after d. When fn does not complete in time,
+// the returned error matches ErrTimeout, a cancelled parent context is returned as is.
+//
+// In ModeOptimistic, Execute waits for fn to return and only reports a timeout when fn fails
+// after the deadline, a result returned despite the deadline is kept. In ModePessimistic,
+// Execute returns as soon as the deadline expires and fn keeps running in the background.
+func Execute[T any](ctx context.Context, d time.Duration, fn func(context.Context) (T, error), opts ...Option) (T, error) {
+	var config Config
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	timeoutCtx, cancel := context.WithTimeoutCause(ctx, d, ErrTimeout)
+	defer cancel()
+
+	start := time.Now()
+
+	if config.Mode == ModePessimistic {
+		return executePessimistic(ctx, timeoutCtx, &config, d, start, fn)
+	}
+
+	result, err := fn(timeoutCtx)
+	if err != nil && errors.Is(context.Cause(timeoutCtx), ErrTimeout) {
+		return zero, timedOut(ctx, &config, d, start, err)
+	}
+
+	return result, err
+}
+
+type completion[T any] struct {
+	result    T
+	err       error
+	recovered any
+	panicked  bool
+}
+
+const (
+	stateRunning int32 = iota
+	stateCompleted
+	stateAbandoned
+)
+
+func executePessimistic[T any](
+	ctx, timeoutCtx context.Context,
+	config *Config,
+	d time.Duration,
+	start time.Time,
+	fn func(context.Context) (T, error),
+) (T, error) {
+	var (
+		zero  T
+		state atomic.Int32
+		done  = make(chan completion[T], 1)
+	)
+
+	go func() {
+		var c completion[T]
+		defer func() {
+			if r := recover(); r != nil {
+				c = completion[T]{recovered: r, panicked: true}
+			}
+
+			if state.CompareAndSwap(stateRunning, stateCompleted) {
+				done <- c
+				return
+			}
+
+			reportAbandoned(ctx, config, start, c)
+		}()
+
+		c.result, c.err = fn(timeoutCtx)
+	}()
+
+	select {
+	case c := <-done:
+		return c.unwrap()
+	case <-timeoutCtx.Done():
+	}
+
+	if !state.CompareAndSwap(stateRunning, stateAbandoned) {
+		// the operation completed while the timeout fired, prefer its result
+		return (<-done).unwrap()
+	}
+
+	if !errors.Is(context.Cause(timeoutCtx), ErrTimeout) {
+		return zero, ctx.Err()
+	}
+
+	return zero, timedOut(ctx, config, d, start, nil)
+}
+
+func (c completion[T]) unwrap() (T, error) {
+	if c.panicked {
+		panic(c.recovered)
+	}
+
+	return c.result, c.err
+}
+
+func reportAbandoned[T any](ctx context.Context, config *Config, start time.Time, c completion[T]) {
+	err := c.err
+	if c.panicked {
+		err = &PanicError{Recover: c.recovered}
+	}
+
+	config.metricsReporter().RecordAbandonedCompletion(
+		context.WithoutCancel(ctx), AbandonedCompletion{
+			Name:     config.Name,
+			Duration: time.Since(start),
+			Error:    err,
+		},
+	)
+
+	if config.OnAbandon != nil {
+		config.OnAbandon(c.result, err)
+	}
+}
+
+func timedOut(ctx context.Context, config *Config, d time.Duration, start time.Time, cause error) error {
+	config.metricsReporter().RecordTimeout(
+		ctx, Timeout{
+			Name:    config.Name,
+			Mode:    config.Mode,
+			Timeout: d,
+			Elapsed: time.Since(start),
+		},
+	)
+
+	return &Error{Timeout: d, Mode: config.Mode, Cause: cause}
+}
+
+func Do(ctx context.Context, d time.Duration, fn func(context.Context) error, opts ...Option) error {
+	_, err := Execute(ctx, d, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, opts...)
+
+	return err
+}
@@ -0,0 +1,110 @@
+package timeout_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hugolhafner/dskit/timeout"
+)
+
+type recordingMetrics struct {
+	mu        sync.Mutex
+	timeouts  []timeout.Timeout
+	abandoned []timeout.AbandonedCompletion
+}
+
+func (r *recordingMetrics) RecordTimeout(_ context.Context, t timeout.Timeout) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timeouts = append(r.timeouts, t)
+}
+
+func (r *recordingMetrics) RecordAbandonedCompletion(_ context.Context, c timeout.AbandonedCompletion) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.abandoned = append(r.abandoned, c)
+}
+
+func waitForCancel(ctx context.Context) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestExecute_Optimistic(t *testing.T) {
+	metrics := &recordingMetrics{}
+
+	_, err := timeout.Execute(
+		context.Background(), 10*time.Millisecond, waitForCancel,
+		timeout.WithName("optimistic"), timeout.WithMetrics(metrics),
+	)
+	require.ErrorIs(t, err, timeout.ErrTimeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, metrics.timeouts, 1)
+	require.Equal(t, "optimistic", metrics.timeouts[0].Name)
+
+	result, err := timeout.Execute(context.Background(), time.Second, func(context.Context) (string, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "ok", result)
+}
+
+func TestExecute_ParentCancellationIsNotATimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	_, err := timeout.Execute(ctx, time.Second, waitForCancel)
+	require.ErrorIs(t, err, context.Canceled)
+	require.False(t, timeout.IsTimeoutError(err))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = timeout.Execute(ctx, time.Second, waitForCancel, timeout.WithMode(timeout.ModePessimistic))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.False(t, timeout.IsTimeoutError(err))
+}
+
+func TestExecute_PessimisticAbandons(t *testing.T) {
+	metrics := &recordingMetrics{}
+	release := make(chan struct{})
+	abandoned := make(chan error, 1)
+	errLate := errors.New("late")
+
+	start := time.Now()
+	_, err := timeout.Execute(
+		context.Background(), 10*time.Millisecond,
+		func(context.Context) (int, error) {
+			// ignores its context
+			<-release
+			return 0, errLate
+		},
+		timeout.WithMode(timeout.ModePessimistic),
+		timeout.WithMetrics(metrics),
+		timeout.WithOnAbandon(func(_ any, err error) { abandoned <- err }),
+	)
+	require.ErrorIs(t, err, timeout.ErrTimeout)
+	require.Less(t, time.Since(start), time.Second)
+
+	close(release)
+	require.ErrorIs(t, <-abandoned, errLate)
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	require.Len(t, metrics.timeouts, 1)
+	require.Equal(t, timeout.ModePessimistic, metrics.timeouts[0].Mode)
+	require.Len(t, metrics.abandoned, 1)
+}
+
+func TestExecute_PessimisticPropagatesPanic(t *testing.T) {
+	require.PanicsWithValue(t, "boom", func() {
+		_ = timeout.Do(context.Background(), time.Second, func(context.Context) error {
+			panic("boom")
+		}, timeout.WithMode(timeout.ModePessimistic))
+	})
+}
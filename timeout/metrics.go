@@ -0,0 +1,64 @@
+package timeout
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+var _ Metrics = (*NoopMetrics)(nil)
+
+var _globalMetrics atomic.Pointer[Metrics]
+
+// Timeout represents an operation that exceeded its timeout
+type Timeout struct {
+	Name    string
+	Mode    Mode
+	Timeout time.Duration
+	Elapsed time.Duration
+}
+
+// AbandonedCompletion represents an abandoned pessimistic operation that eventually returned
+type AbandonedCompletion struct {
+	Name     string
+	Duration time.Duration
+	Error    error
+}
+
+// Metrics defines the interface for timeout instrumentation
+type Metrics interface {
+	// RecordTimeout records an operation that exceeded its timeout
+	RecordTimeout(ctx context.Context, timeout Timeout)
+
+	// RecordAbandonedCompletion records an abandoned operation returning after the caller gave up on it
+	RecordAbandonedCompletion(ctx context.Context, completion AbandonedCompletion)
+}
+
+// NoopMetrics is a no-operation implementation of the Metrics interface
+type NoopMetrics struct{}
+
+func (n *NoopMetrics) RecordTimeout(_ context.Context, _ Timeout) {
+	// No-op
+}
+
+func (n *NoopMetrics) RecordAbandonedCompletion(_ context.Context, _ AbandonedCompletion) {
+	// No-op
+}
+
+// SetGlobalMetrics sets the global Metrics implementation
+func SetGlobalMetrics(m Metrics) {
+	if m == nil {
+		m = &NoopMetrics{}
+	}
+
+	_globalMetrics.Store(&m)
+}
+
+// GetGlobalMetrics returns the global Metrics implementation
+func GetGlobalMetrics() Metrics {
+	m := _globalMetrics.Load()
+	if m == nil {
+		return &NoopMetrics{}
+	}
+	return *m
+}
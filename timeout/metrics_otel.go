@@ -0,0 +1,136 @@
+package timeout
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metrics:
+// timeout_timeouts_total (Counter) - Total number of operations that exceeded their timeout
+// * name (string) - The name given with WithName
+// * mode (string) - The timeout mode ("optimistic", "pessimistic")
+//
+// timeout_abandoned_total (Counter) - Total number of abandoned operations that eventually returned
+// * name (string) - The name given with WithName
+// * status (string) - How the abandoned operation returned ("success", "error")
+//
+// timeout_abandoned_duration_milliseconds (Histogram) - Total duration of abandoned operations in milliseconds
+// * name (string) - The name given with WithName
+
+const (
+	instrumentationName    = "github.com/hugolhafner/dskit/timeout"
+	instrumentationVersion = "v0.1.0" // x-release-please
+)
+
+const (
+	unitOperation    = "{operation}"
+	unitMilliseconds = "ms"
+)
+
+var _ Metrics = (*OTelMetrics)(nil)
+
+type OTelMetrics struct {
+	timeoutsTotal     metric.Int64Counter
+	abandonedTotal    metric.Int64Counter
+	abandonedDuration metric.Float64Histogram
+}
+
+type OTelConfig struct {
+	MeterProvider metric.MeterProvider
+	MetricPrefix  string
+}
+
+type OTelOption func(*OTelConfig)
+
+func WithMeterProvider(meterProvider metric.MeterProvider) OTelOption {
+	return func(cfg *OTelConfig) {
+		cfg.MeterProvider = meterProvider
+	}
+}
+
+func WithMetricPrefix(prefix string) OTelOption {
+	return func(cfg *OTelConfig) {
+		cfg.MetricPrefix = prefix
+	}
+}
+
+func NewOTelMetrics(opts ...OTelOption) (*OTelMetrics, error) {
+	cfg := &OTelConfig{
+		MeterProvider: otel.GetMeterProvider(),
+		MetricPrefix:  "timeout_",
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	meter := cfg.MeterProvider.Meter(instrumentationName, metric.WithInstrumentationVersion(instrumentationVersion))
+
+	timeoutsTotal, err := meter.Int64Counter(
+		cfg.MetricPrefix+"timeouts_total",
+		metric.WithDescription("Total number of operations that exceeded their timeout"),
+		metric.WithUnit(unitOperation),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create timeouts_total counter: %w", err)
+	}
+
+	abandonedTotal, err := meter.Int64Counter(
+		cfg.MetricPrefix+"abandoned_total",
+		metric.WithDescription("Total number of abandoned operations that eventually returned"),
+		metric.WithUnit(unitOperation),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create abandoned_total counter: %w", err)
+	}
+
+	abandonedDuration, err := meter.Float64Histogram(
+		cfg.MetricPrefix+"abandoned_duration_milliseconds",
+		metric.WithDescription("Total duration of abandoned operations in milliseconds"),
+		metric.WithUnit(unitMilliseconds),
+		metric.WithExplicitBucketBoundaries(0, 1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create abandoned_duration_milliseconds histogram: %w", err)
+	}
+
+	return &OTelMetrics{
+		timeoutsTotal:     timeoutsTotal,
+		abandonedTotal:    abandonedTotal,
+		abandonedDuration: abandonedDuration,
+	}, nil
+}
+
+func MustNewOTelMetrics(opts ...OTelOption) *OTelMetrics {
+	m, err := NewOTelMetrics(opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+func (m *OTelMetrics) RecordTimeout(ctx context.Context, timeout Timeout) {
+	m.timeoutsTotal.Add(
+		ctx, 1, metric.WithAttributes(
+			attribute.String("name", timeout.Name),
+			attribute.String("mode", timeout.Mode.String()),
+		),
+	)
+}
+
+func (m *OTelMetrics) RecordAbandonedCompletion(ctx context.Context, completion AbandonedCompletion) {
+	status := "success"
+	if completion.Error != nil {
+		status = "error"
+	}
+
+	nameAttr := attribute.String("name", completion.Name)
+
+	m.abandonedTotal.Add(ctx, 1, metric.WithAttributes(nameAttr, attribute.String("status", status)))
+	m.abandonedDuration.Record(ctx, float64(completion.Duration.Milliseconds()), metric.WithAttributes(nameAttr))
+}